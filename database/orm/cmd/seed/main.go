@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"log"
+	"os"
+
+	"github.com/go-examples-with-tests/database/orm"
+	ormlog "github.com/go-examples-with-tests/database/orm/log"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// 将 Engine.Export/ExportSQL 导出的文件导入到数据库中，用于初始化开发环境的数据。
+// 默认只创建不存在的表并追加记录，-drop 时先删除同名的表，表中已有的数据会丢失，例如：
+//
+//	go run ./database/orm/cmd/seed -source dev.db users.jsonl accounts.sql
+//	cat users.jsonl | go run ./database/orm/cmd/seed -source dev.db -drop
+func main() {
+	driver := flag.String("driver", "sqlite3", "database driver name")
+	source := flag.String("source", "gee.db", "data source name")
+	verbose := flag.Bool("v", false, "print executed SQL statements")
+	drop := flag.Bool("drop", false, "drop existing tables before importing, existing data will be lost")
+	flag.Parse()
+
+	if !*verbose {
+		ormlog.SetLevel(ormlog.ErrorLevel)
+	}
+
+	engine, err := orm.NewEngine(*driver, *source)
+	if err != nil {
+		log.Fatal(err)
+	}
+	defer engine.Close()
+
+	var opts []orm.ImportOption
+	if *drop {
+		opts = append(opts, orm.DropTables())
+	}
+	if flag.NArg() == 0 {
+		if err := engine.Import(os.Stdin, opts...); err != nil {
+			log.Fatalf("import from stdin: %v", err)
+		}
+		return
+	}
+	for _, name := range flag.Args() {
+		if err := importFile(engine, name, opts...); err != nil {
+			log.Fatalf("import %s: %v", name, err)
+		}
+		log.Printf("import %s success", name)
+	}
+}
+
+func importFile(engine *orm.Engine, name string, opts ...orm.ImportOption) error {
+	f, err := os.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return engine.Import(f, opts...)
+}
@@ -0,0 +1,328 @@
+package orm
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/go-examples-with-tests/database/orm/clause"
+	"github.com/go-examples-with-tests/database/orm/schema"
+	"github.com/go-examples-with-tests/database/orm/session"
+)
+
+// record 是 JSON Lines 导出格式中的一行：
+// 每张表先输出一行表结构 {"table":"User","columns":[...]}，之后每条记录一行 {"table":"User","values":[...]}
+type record struct {
+	Table   string          `json:"table"`
+	Columns []*schema.Field `json:"columns,omitempty"`
+	Values  []interface{}   `json:"values,omitempty"`
+}
+
+// maxImportVars 限制一条 INSERT 语句中 ? 占位符的个数，sqlite3 默认最多 999 个
+const maxImportVars = 999
+
+// Export 以 JSON Lines 格式导出 models 对应的表结构和所有记录，导出的内容可以由 Import 恢复
+func (engine *Engine) Export(w io.Writer, models ...interface{}) error {
+	encoder := json.NewEncoder(w)
+	return engine.export(models, func(table *schema.Schema) error {
+		return encoder.Encode(&record{Table: table.Name, Columns: table.Fields})
+	}, func(table *schema.Schema, values []interface{}) error {
+		return encoder.Encode(&record{Table: table.Name, Values: values})
+	})
+}
+
+// ExportSQL 以 SQL 语句的形式导出 models 对应的表：CREATE TABLE IF NOT EXISTS 以及每条记录的 INSERT，
+// 不包含 DROP TABLE，直接执行导出的脚本也不会删除已有的数据
+func (engine *Engine) ExportSQL(w io.Writer, models ...interface{}) error {
+	return engine.export(models, func(table *schema.Schema) error {
+		_, err := fmt.Fprintln(w, createTableSQL(table))
+		return err
+	}, func(table *schema.Schema, values []interface{}) error {
+		literals := make([]string, 0, len(values))
+		for _, v := range values {
+			literals = append(literals, sqlLiteral(v))
+		}
+		_, err := fmt.Fprintf(w, "INSERT INTO %s (%s) VALUES (%s);\n",
+			table.Name, strings.Join(table.FieldNames, ","), strings.Join(literals, ", "))
+		return err
+	})
+}
+
+// export 逐表、逐行读取 models 对应的记录，不会一次性把整张表加载到内存中
+// 直接读取表中的原始数据，不调用 BeforeQuery/AfterQuery 钩子，否则钩子对记录的修改（例如隐藏密码）会被写入备份
+func (engine *Engine) export(models []interface{}, header func(*schema.Schema) error,
+	row func(*schema.Schema, []interface{}) error) error {
+	for _, model := range models {
+		s := engine.NewSession()
+		table := s.Model(model).RefTable()
+		if err := header(table); err != nil {
+			return err
+		}
+
+		var c clause.Clause
+		c.Set(clause.SELECT, table.Name, table.FieldNames)
+		sql, vars := c.Build(clause.SELECT)
+		rows, err := s.Raw(sql, vars...).QueryRows()
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			dest := reflect.ValueOf(newModel(model)).Elem()
+			fields := make([]interface{}, 0, len(table.FieldNames))
+			for _, name := range table.FieldNames {
+				fields = append(fields, dest.FieldByName(name).Addr().Interface())
+			}
+			if err = rows.Scan(fields...); err != nil {
+				break
+			}
+			if err = row(table, table.RecordValues(dest.Addr().Interface())); err != nil {
+				break
+			}
+		}
+		if err == nil {
+			err = rows.Err()
+		}
+		_ = rows.Close()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ImportOption 是 Import 的可选配置
+type ImportOption func(*importOptions)
+
+type importOptions struct {
+	dropTables bool
+}
+
+// DropTables 使 Import 在建表之前删除同名的表，导入的结果与导出时完全一致，表中已有的数据会丢失
+func DropTables() ImportOption {
+	return func(o *importOptions) {
+		o.dropTables = true
+	}
+}
+
+// Import 导入 Export 或 ExportSQL 导出的内容，默认只创建不存在的表，记录追加到已有的表中，
+// 主键冲突时整个导入回滚；使用 DropTables 时先删除同名的表再重新建表。
+// JSON Lines 格式在同一个事务中批量插入所有记录；SQL 格式则在事务中逐条执行。
+// 导入时只有表结构，没有对应的 Model，所以不经过 Session.Insert，也不调用 BeforeInsert/AfterInsert 钩子：
+// 备份中是表中的原始数据，钩子（例如对密码做哈希）再处理一次反而会破坏数据
+func (engine *Engine) Import(r io.Reader, opts ...ImportOption) error {
+	var o importOptions
+	for _, opt := range opts {
+		opt(&o)
+	}
+	reader := bufio.NewReader(r)
+	first, err := peekNonSpace(reader)
+	if err != nil {
+		if err == io.EOF {
+			return nil
+		}
+		return err
+	}
+	if first != '{' {
+		return engine.importSQL(reader, o)
+	}
+
+	_, err = engine.Transaction(func(s *session.Session) (interface{}, error) {
+		var table *schema.Schema
+		var rows []interface{}
+		flush := func() error {
+			if len(rows) == 0 {
+				return nil
+			}
+			var c clause.Clause
+			c.Set(clause.INSERT, table.Name, table.FieldNames)
+			c.Set(clause.VALUES, rows...)
+			sql, vars := c.Build(clause.INSERT, clause.VALUES)
+			rows = rows[:0]
+			_, err := s.Raw(sql, vars...).Exec()
+			return err
+		}
+
+		decoder := json.NewDecoder(reader)
+		decoder.UseNumber()
+		for {
+			var rec record
+			if err := decoder.Decode(&rec); err == io.EOF {
+				break
+			} else if err != nil {
+				return nil, err
+			}
+
+			if rec.Columns != nil {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+				table = schema.New(rec.Table, rec.Columns)
+				s.Schema(table)
+				if o.dropTables {
+					if err := s.Table(table.Name).DropTable(); err != nil {
+						return nil, err
+					}
+				}
+				if !s.Table(table.Name).HasTable() {
+					if err := s.Table(table.Name).CreateTable(); err != nil {
+						return nil, err
+					}
+				}
+				continue
+			}
+
+			if table == nil || table.Name != rec.Table {
+				return nil, fmt.Errorf("import: missing columns of table %s", rec.Table)
+			}
+			if len(rec.Values) != len(table.Fields) {
+				return nil, fmt.Errorf("import: table %s want %d values, got %d", rec.Table, len(table.Fields), len(rec.Values))
+			}
+			values := make([]interface{}, 0, len(rec.Values))
+			for i, v := range rec.Values {
+				value, err := decodeValue(table.Fields[i].Type, v)
+				if err != nil {
+					return nil, err
+				}
+				values = append(values, value)
+			}
+			rows = append(rows, values)
+			if len(rows)*len(table.Fields) >= maxImportVars-len(table.Fields) {
+				if err := flush(); err != nil {
+					return nil, err
+				}
+			}
+		}
+		return nil, flush()
+	})
+	return err
+}
+
+func (engine *Engine) importSQL(r io.Reader, o importOptions) error {
+	script, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	_, err = engine.Transaction(func(s *session.Session) (interface{}, error) {
+		for _, stmt := range splitStatements(string(script)) {
+			if name, ok := createdTable(stmt); ok && o.dropTables {
+				if _, err := s.Raw("DROP TABLE IF EXISTS " + name).Exec(); err != nil {
+					return nil, err
+				}
+			}
+			if _, err := s.Raw(stmt).Exec(); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	})
+	return err
+}
+
+func createTableSQL(table *schema.Schema) string {
+	var columns []string
+	for _, field := range table.Fields {
+		columns = append(columns, fmt.Sprintf("%s %s %s", field.Name, field.Type, field.Tag))
+	}
+	return fmt.Sprintf("%s %s (%s);", createTablePrefix, table.Name, strings.Join(columns, ","))
+}
+
+const createTablePrefix = "CREATE TABLE IF NOT EXISTS"
+
+// createdTable 返回 ExportSQL 导出的建表语句中的表名，不是建表语句时返回 false
+func createdTable(stmt string) (string, bool) {
+	if !strings.HasPrefix(stmt, createTablePrefix+" ") {
+		return "", false
+	}
+	name := strings.Fields(stmt[len(createTablePrefix):])[0]
+	return strings.TrimSuffix(name, "("), true
+}
+
+// sqlLiteral 将 Go 值转换为 SQL 字面量
+func sqlLiteral(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return "NULL"
+	case string:
+		return "'" + strings.ReplaceAll(v, "'", "''") + "'"
+	case []byte:
+		return fmt.Sprintf("X'%x'", v)
+	case bool:
+		if v {
+			return "1"
+		}
+		return "0"
+	case time.Time:
+		return "'" + v.Format("2006-01-02 15:04:05.999999999-07:00") + "'"
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// decodeValue 依据列的类型，将 JSON 中解析出的值还原为对应的 Go 值
+func decodeValue(typ string, value interface{}) (interface{}, error) {
+	switch v := value.(type) {
+	case json.Number:
+		if n, err := v.Int64(); err == nil {
+			return n, nil
+		}
+		return v.Float64()
+	case string:
+		switch typ {
+		case "blob":
+			return base64.StdEncoding.DecodeString(v)
+		case "datetime":
+			return time.Parse(time.RFC3339Nano, v)
+		}
+	}
+	return value, nil
+}
+
+// splitStatements 按 ; 拆分 SQL 脚本，忽略单引号字符串中的 ;
+func splitStatements(script string) []string {
+	var stmts []string
+	var stmt strings.Builder
+	quoted := false
+	for _, c := range script {
+		if c == '\'' {
+			quoted = !quoted
+		}
+		if c == ';' && !quoted {
+			if s := strings.TrimSpace(stmt.String()); s != "" {
+				stmts = append(stmts, s)
+			}
+			stmt.Reset()
+			continue
+		}
+		stmt.WriteRune(c)
+	}
+	if s := strings.TrimSpace(stmt.String()); s != "" {
+		stmts = append(stmts, s)
+	}
+	return stmts
+}
+
+// peekNonSpace 跳过开头的空白字符，返回第一个非空白字符但不消费它
+func peekNonSpace(r *bufio.Reader) (byte, error) {
+	for {
+		c, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		switch c {
+		case ' ', '\t', '\r', '\n':
+			continue
+		}
+		return c, r.UnreadByte()
+	}
+}
+
+// newModel 创建一个与 model 类型相同的结构体指针
+func newModel(model interface{}) interface{} {
+	return reflect.New(reflect.Indirect(reflect.ValueOf(model)).Type()).Interface()
+}
@@ -0,0 +1,201 @@
+package orm
+
+import (
+	"bytes"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-examples-with-tests/database/orm/session"
+)
+
+type Seed struct {
+	Name     string `geeorm:"PRIMARY KEY"`
+	Age      int
+	Score    float64
+	Avatar   []byte
+	Birthday time.Time
+}
+
+func newSeedEngine(t *testing.T, name string) *Engine {
+	t.Helper()
+	engine, err := NewEngine("sqlite3", filepath.Join(t.TempDir(), name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return engine
+}
+
+func seeds() []interface{} {
+	birthday := time.Date(2000, 1, 2, 3, 4, 5, 0, time.UTC)
+	return []interface{}{
+		&Seed{"Tom", 18, 90.5, []byte{0x1, 0x2}, birthday},
+		&Seed{"O'Neil; Sam", 20, 60, nil, birthday},
+	}
+}
+
+func testDump(t *testing.T, export func(*Engine, *bytes.Buffer) error) {
+	src := newSeedEngine(t, "src.db")
+	defer src.Close()
+	s := src.NewSession()
+	_ = s.Model(&Seed{}).CreateTable()
+	if _, err := s.Insert(seeds()...); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := export(src, &buf); err != nil {
+		t.Fatal(err)
+	}
+	t.Log(buf.String())
+
+	dst := newSeedEngine(t, "dst.db")
+	defer dst.Close()
+	// 默认追加记录，再次导入时主键冲突，整个导入回滚
+	if err := dst.Import(bytes.NewReader(buf.Bytes())); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.Import(bytes.NewReader(buf.Bytes())); err == nil {
+		t.Fatal("expect primary key conflict when importing twice")
+	}
+	// DropTables 时重新建表，而不是追加记录
+	if err := dst.Import(bytes.NewReader(buf.Bytes()), DropTables()); err != nil {
+		t.Fatal(err)
+	}
+
+	var got []Seed
+	if err := dst.NewSession().OrderBy("Age").Find(&got); err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 {
+		t.Fatal("want 2 seeds, got:", got)
+	}
+	for i, want := range seeds() {
+		w := want.(*Seed)
+		if got[i].Name != w.Name || got[i].Age != w.Age || got[i].Score != w.Score ||
+			!bytes.Equal(got[i].Avatar, w.Avatar) || !got[i].Birthday.Equal(w.Birthday) {
+			t.Fatalf("seed %d mismatch, want %+v, got %+v", i, w, got[i])
+		}
+	}
+}
+
+func TestExportImport(t *testing.T) {
+	testDump(t, func(e *Engine, buf *bytes.Buffer) error {
+		return e.Export(buf, &Seed{})
+	})
+}
+
+func TestExportSQLImport(t *testing.T) {
+	testDump(t, func(e *Engine, buf *bytes.Buffer) error {
+		return e.ExportSQL(buf, &Seed{})
+	})
+}
+
+type Secret struct {
+	Name     string `geeorm:"PRIMARY KEY"`
+	Password string
+}
+
+// AfterQuery 在查询结果中隐藏密码
+func (a *Secret) AfterQuery(s *session.Session) error {
+	a.Password = "******"
+	return nil
+}
+
+func TestExportSkipsHooks(t *testing.T) {
+	for name, export := range map[string]func(*Engine, *bytes.Buffer) error{
+		"jsonl": func(e *Engine, buf *bytes.Buffer) error { return e.Export(buf, &Secret{}) },
+		"sql":   func(e *Engine, buf *bytes.Buffer) error { return e.ExportSQL(buf, &Secret{}) },
+	} {
+		src := newSeedEngine(t, name+"_src.db")
+		s := src.NewSession()
+		_ = s.Model(&Secret{}).CreateTable()
+		if _, err := s.Insert(&Secret{"Tom", "secret"}); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := export(src, &buf); err != nil {
+			t.Fatal(err)
+		}
+		src.Close()
+
+		dst := newSeedEngine(t, name+"_dst.db")
+		if err := dst.Import(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var password string
+		row := dst.NewSession().Raw("SELECT Password FROM Secret WHERE Name = ?", "Tom").QueryRow()
+		if err := row.Scan(&password); err != nil || password != "secret" {
+			t.Fatalf("%s: want raw password in backup, got %q, err: %v", name, password, err)
+		}
+		dst.Close()
+	}
+}
+
+func TestImportRollback(t *testing.T) {
+	engine := newSeedEngine(t, "rollback.db")
+	defer engine.Close()
+
+	dump := `{"table":"Seed","columns":[{"Name":"Name","Type":"text","Tag":""}]}
+{"table":"Seed","values":["Tom"]}
+{"table":"Seed","values":["Tom","extra"]}
+`
+	if err := engine.Import(strings.NewReader(dump)); err == nil {
+		t.Fatal("expect error for mismatched values")
+	}
+	if engine.NewSession().Model(&Seed{}).HasTable() {
+		t.Fatal("import should be rolled back")
+	}
+}
+
+func TestImportKeepsExistingRows(t *testing.T) {
+	for name, export := range map[string]func(*Engine, *bytes.Buffer) error{
+		"jsonl": func(e *Engine, buf *bytes.Buffer) error { return e.Export(buf, &Seed{}) },
+		"sql":   func(e *Engine, buf *bytes.Buffer) error { return e.ExportSQL(buf, &Seed{}) },
+	} {
+		src := newSeedEngine(t, name+"_src.db")
+		s := src.NewSession()
+		_ = s.Model(&Seed{}).CreateTable()
+		if _, err := s.Insert(&Seed{Name: "Tom", Age: 18}); err != nil {
+			t.Fatal(err)
+		}
+		var buf bytes.Buffer
+		if err := export(src, &buf); err != nil {
+			t.Fatal(err)
+		}
+		src.Close()
+
+		dst := newSeedEngine(t, name+"_dst.db")
+		s = dst.NewSession()
+		_ = s.Model(&Seed{}).CreateTable()
+		if _, err := s.Insert(&Seed{Name: "Sam", Age: 20}); err != nil {
+			t.Fatal(err)
+		}
+		if err := dst.Import(&buf); err != nil {
+			t.Fatal(err)
+		}
+		if count, err := dst.NewSession().Model(&Seed{}).Count(); err != nil || count != 2 {
+			t.Fatalf("%s: existing rows should be kept, got %d rows, err: %v", name, count, err)
+		}
+		dst.Close()
+	}
+}
+
+func TestCreatedTable(t *testing.T) {
+	if name, ok := createdTable("CREATE TABLE IF NOT EXISTS Seed (Name text)"); !ok || name != "Seed" {
+		t.Fatal("failed to parse table name, got:", name)
+	}
+	if _, ok := createdTable("INSERT INTO Seed (Name) VALUES ('CREATE TABLE IF NOT EXISTS')"); ok {
+		t.Fatal("INSERT is not a create table statement")
+	}
+}
+
+func TestSplitStatements(t *testing.T) {
+	got := splitStatements("INSERT INTO T VALUES ('a;b');\n\nDELETE FROM T;")
+	want := []string{"INSERT INTO T VALUES ('a;b')", "DELETE FROM T"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatal("failed to split statements, got:", got)
+	}
+}
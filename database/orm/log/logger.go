@@ -0,0 +1,54 @@
+package log
+
+import (
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+	"sync"
+)
+
+var (
+	errorLog = log.New(os.Stdout, "\033[31m[error]\033[0m", log.LstdFlags|log.Lshortfile)
+	infoLog  = log.New(os.Stdout, "\033[32m[info ]\033[0m", log.LstdFlags|log.Lshortfile)
+	loggers  = []*log.Logger{errorLog, infoLog}
+	mu       sync.Mutex
+)
+
+// log method
+var (
+	Error  = errorLog.Println
+	Errorf = errorLog.Printf
+	Info   = infoLog.Println
+	Infof  = infoLog.Printf
+)
+
+const (
+	InfoLevel = iota
+	ErrorLevel
+	Disable
+)
+
+func SetLevel(level int) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	if ErrorLevel < level {
+		errorLog.SetOutput(ioutil.Discard)
+	}
+	if InfoLevel < level {
+		infoLog.SetOutput(ioutil.Discard)
+	}
+}
+
+// SetOutput 设置日志的输出，已经被 SetLevel 关闭的日志仍然不输出
+func SetOutput(w io.Writer) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	for _, logger := range loggers {
+		if logger.Writer() != ioutil.Discard {
+			logger.SetOutput(w)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package log
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+)
+
+// SetLevel 关闭的日志无法恢复，需要在 TestLogger 之前运行
+func TestSetOutput(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	defer SetOutput(os.Stdout)
+
+	Error("error message")
+	if errorLog.Writer() != &buf || !strings.Contains(buf.String(), "error message") {
+		t.Fatal("failed to set output, got:", buf.String())
+	}
+}
+
+func TestLogger(t *testing.T) {
+	SetLevel(ErrorLevel)
+	if errorLog.Writer() == ioutil.Discard || infoLog.Writer() != ioutil.Discard {
+		t.Fatal("errorLog's writer must be ioutil.Discard and infoLog's writer must not be ioutil.Discard")
+	}
+
+	SetLevel(Disable)
+	if errorLog.Writer() != ioutil.Discard || infoLog.Writer() != ioutil.Discard {
+		t.Fatal("errorLog and infoLog's writer must be ioutil.Discard")
+	}
+}
@@ -0,0 +1,159 @@
+package orm
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/go-examples-with-tests/database/orm/dialect"
+	"github.com/go-examples-with-tests/database/orm/log"
+	"github.com/go-examples-with-tests/database/orm/session"
+)
+
+type Engine struct {
+	db       *sql.DB
+	dialect  dialect.Dialect
+	sharding session.ShardingFunc
+}
+
+func NewEngine(driver, source string) (e *Engine, err error) {
+	db, err := sql.Open(driver, source)
+	if err != nil {
+		log.Error(err)
+		return
+	}
+
+	if err = db.Ping(); err != nil {
+		log.Error(err)
+		return
+	}
+
+	dial, ok := dialect.GetDialect(driver)
+	if !ok {
+		err = fmt.Errorf("get dialect: %s error", driver)
+		log.Error(err)
+		_ = db.Close()
+		return
+	}
+
+	e = &Engine{db: db, dialect: dial}
+	log.Info("Connect database success")
+	return
+}
+
+func (engine *Engine) Close() {
+	if err := engine.db.Close(); err != nil {
+		log.Error("Failed to close database")
+	}
+	log.Info("Close database success")
+}
+
+func (engine *Engine) DB() *sql.DB {
+	return engine.db
+}
+
+func (engine *Engine) NewSession() *session.Session {
+	return session.New(engine.db, engine.dialect).Sharding(engine.sharding)
+}
+
+// Sharding 设置分表函数，由 Engine 创建的 Session 在 Insert/Find 等操作时依据它路由到对应的分表
+func (engine *Engine) Sharding(f session.ShardingFunc) {
+	engine.sharding = f
+}
+
+type TxFunc func(*session.Session) (interface{}, error)
+
+func (engine *Engine) Transaction(f TxFunc) (result interface{}, err error) {
+	session := engine.NewSession()
+	if err = session.Begin(); err != nil {
+		log.Error(err)
+		return nil, err
+	}
+
+	defer func() {
+		log.Info("Transaction run...")
+		if p := recover(); p != nil {
+			session.Rollback()
+			panic(p) // re-throw panic after Rollback
+		} else if err != nil {
+			log.Error(err.Error())
+			_ = session.Rollback() // err is non-nil; don't change it
+		} else {
+			err = session.Commit() // err is nil; if Commit returns error update err
+		}
+	}()
+	// 执行顺序：f(session) --> defer func(){}() 此时 err 变量已被 f(session) 赋值
+	return f(session)
+}
+
+// difference get the difference of a - b
+func difference(a, b []string) (diff []string) {
+	mapD := make(map[string]bool)
+	for _, v := range b {
+		mapD[v] = true
+	}
+
+	for _, v := range a {
+		if _, ok := mapD[v]; !ok {
+			diff = append(diff, v)
+		}
+	}
+	return diff
+}
+
+// Migrate 依据 value 的结构迁移表，tables 非空时逐一迁移（或创建）其中的每一张分表
+func (engine *Engine) Migrate(value interface{}, tables ...string) error {
+	_, err := engine.Transaction(func(s *session.Session) (result interface{}, err error) {
+		if len(tables) == 0 {
+			tables = append(tables, s.Model(value).TableName())
+		}
+		for _, name := range tables {
+			if err = migrate(s, value, name); err != nil {
+				return
+			}
+		}
+		return
+	})
+
+	return err
+}
+
+func migrate(s *session.Session, value interface{}, name string) (err error) {
+	// value interface{} --> new table with column changed
+	if !s.Model(value).Table(name).HasTable() {
+		log.Infof("table %s doesn't exist", name)
+		return s.Table(name).CreateTable()
+	}
+
+	table := s.RefTable()
+	// 虽然此处 table 的 column 改变了，但是 table_name 没有改变
+	rows, _ := s.Raw(fmt.Sprintf("SELECT * FROM %s LIMIT 1;", name)).QueryRows()
+	columns, _ := rows.Columns()
+	_ = rows.Close()
+	log.Infof("origin table columns:%v", columns)
+
+	addCols := difference(table.FieldNames, columns) // new - old = 在 new 中挑选 old 没有的
+	delCols := difference(columns, table.FieldNames) // old - new = 在 old 中挑选 new 没有的
+	log.Infof("added cols:%v, deleted cols:%s", addCols, delCols)
+
+	for _, col := range addCols {
+		field := table.GetField(col)
+		sqlStr := fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s;", name, field.Name, field.Type)
+		if _, err = s.Raw(sqlStr).Exec(); err != nil {
+			return
+		}
+	}
+
+	if len(delCols) == 0 {
+		return
+	}
+	tmp := "tmp_" + name
+	fieldStr := strings.Join(table.FieldNames, ", ") // new columns
+	s.Raw(fmt.Sprintf("CREATE TABLE %s AS SELECT %s from %s;", tmp, fieldStr, name))
+	s.Raw(fmt.Sprintf("DROP TABLE %s;", name))
+	s.Raw(fmt.Sprintf("ALTER TABLE %s RENAME TO %s;", tmp, name))
+
+	_, err = s.Exec()
+
+	return
+}
@@ -3,7 +3,7 @@ package schema
 import (
 	"testing"
 
-	"github.com/go-examples-with-tests/database/v2/dialect"
+	"github.com/go-examples-with-tests/database/orm/dialect"
 )
 
 type User struct {
@@ -60,3 +60,11 @@ func TestSchemaPassword(t *testing.T) {
 		t.Fatal("schema parse Password error")
 	}
 }
+
+func TestNew(t *testing.T) {
+	fields := []*Field{{Name: "Name", Type: "text", Tag: "PRIMARY KEY"}, {Name: "Age", Type: "integer"}}
+	schema := New("User", fields)
+	if schema.Name != "User" || len(schema.FieldNames) != 2 || schema.GetField("Age").Type != "integer" {
+		t.Fatal("failed to new schema, got:", schema)
+	}
+}
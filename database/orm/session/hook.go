@@ -3,7 +3,7 @@ package session
 import (
 	"reflect"
 
-	"github.com/go-examples-with-tests/database/v3/log"
+	"github.com/go-examples-with-tests/database/orm/log"
 )
 
 const (
@@ -21,10 +21,12 @@ const (
 )
 
 func (s *Session) CallHoookMethod(method string, value interface{}) {
-	fm := reflect.ValueOf(s.RefTable().Model).MethodByName(method)
+	var fm reflect.Value
 	if value != nil {
 		// 表示 s.RefTable().Model 对应结构体的某个指定变量
 		fm = reflect.ValueOf(value).MethodByName(method)
+	} else if s.refTable != nil {
+		fm = reflect.ValueOf(s.refTable.Model).MethodByName(method)
 	}
 
 	param := []reflect.Value{reflect.ValueOf(s)}
@@ -0,0 +1,139 @@
+package session
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// isNamedArg 判断 Raw 的参数是否为命名参数：SQL 中含有 @name，且仅有一个 map 或结构体参数
+func isNamedArg(sql string, values []interface{}) bool {
+	if len(values) != 1 || !strings.Contains(sql, "@") {
+		return false
+	}
+	switch values[0].(type) {
+	case time.Time, *time.Time, driver.Valuer:
+		return false
+	}
+	v := reflect.Indirect(reflect.ValueOf(values[0]))
+	return (v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String) || v.Kind() == reflect.Struct
+}
+
+// bindNamed 将 sql 中的 @name 替换为 ? 占位符，并按出现顺序从 arg 中取出对应的值
+// arg 可以是 map[string]interface{}，也可以是结构体（按字段名匹配，忽略大小写）
+// 单引号内的字符串以及 MySQL 的 @@ 系统变量不做处理
+func bindNamed(sql string, arg interface{}) (string, []interface{}, error) {
+	argValue := reflect.Indirect(reflect.ValueOf(arg))
+
+	var str strings.Builder
+	var vars []interface{}
+	quoted := false
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		if c == '\'' {
+			quoted = !quoted
+		}
+		if quoted || c != '@' {
+			str.WriteByte(c)
+			continue
+		}
+		if i+1 < len(sql) && sql[i+1] == '@' {
+			str.WriteString("@@")
+			i++
+			continue
+		}
+
+		j := i + 1
+		for j < len(sql) && isIdentChar(sql[j]) {
+			j++
+		}
+		if j == i+1 {
+			str.WriteByte(c)
+			continue
+		}
+		name := sql[i+1 : j]
+		value, ok := namedValue(argValue, name)
+		if !ok {
+			return "", nil, fmt.Errorf("named parameter @%s not found in %T", name, arg)
+		}
+		str.WriteByte('?')
+		vars = append(vars, value)
+		i = j - 1
+	}
+	if quoted {
+		return "", nil, errors.New("unterminated quoted string in sql")
+	}
+	return str.String(), vars, nil
+}
+
+func namedValue(arg reflect.Value, name string) (interface{}, bool) {
+	if arg.Kind() == reflect.Map {
+		v := arg.MapIndex(reflect.ValueOf(name).Convert(arg.Type().Key()))
+		if !v.IsValid() {
+			return nil, false
+		}
+		return v.Interface(), true
+	}
+
+	field := arg.FieldByName(name)
+	if !field.IsValid() {
+		field = arg.FieldByNameFunc(func(n string) bool {
+			return strings.EqualFold(n, name)
+		})
+	}
+	if !field.IsValid() || !field.CanInterface() {
+		return nil, false
+	}
+	return field.Interface(), true
+}
+
+func isIdentChar(c byte) bool {
+	return c == '_' || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z') || ('0' <= c && c <= '9')
+}
+
+// Scan 执行 Raw 设置的查询语句，并将结果按列名映射到 dest 上
+// dest 可以是 *[]T、*[]*T 或 *T（仅取第一行），T 是任意结构体，不要求是已经建表的 Model
+func (s *Session) Scan(dest interface{}) error {
+	destValue := reflect.Indirect(reflect.ValueOf(dest))
+
+	rows, err := s.QueryRows()
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	if destValue.Kind() != reflect.Slice {
+		if !rows.Next() {
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			return errors.New("NOT FOUND")
+		}
+		return s.scan(rows, columns, destValue)
+	}
+
+	elemType := destValue.Type().Elem()
+	isPtr := elemType.Kind() == reflect.Ptr
+	if isPtr {
+		elemType = elemType.Elem()
+	}
+	for rows.Next() {
+		elem := reflect.New(elemType).Elem()
+		if err := s.scan(rows, columns, elem); err != nil {
+			return err
+		}
+		if isPtr {
+			elem = elem.Addr()
+		}
+		destValue.Set(reflect.Append(destValue, elem))
+	}
+	return rows.Err()
+}
@@ -1,11 +1,12 @@
 package session
 
 import (
+	"database/sql"
 	"errors"
 	"reflect"
+	"strings"
 
-	"github.com/go-examples-with-tests/database/v3/clause"
-	"github.com/go-examples-with-tests/database/v3/log"
+	"github.com/go-examples-with-tests/database/orm/clause"
 )
 
 func (s *Session) Limit(num int) *Session {
@@ -20,6 +21,12 @@ func (s *Session) Where(desc string, args ...interface{}) *Session {
 	return s
 }
 
+// whereVars 返回 Where 中的参数，作为分表函数的入参
+func (s *Session) whereVars() []interface{} {
+	_, vars := s.clause.Build(clause.WHERE)
+	return vars
+}
+
 func (s *Session) OrderBy(desc string) *Session {
 	s.clause.Set(clause.ORDERBY, desc)
 	return s
@@ -27,57 +34,91 @@ func (s *Session) OrderBy(desc string) *Session {
 
 func (s *Session) Insert(values ...interface{}) (int64, error) {
 	// INSERT INTO table_name(col1, col2, col3,...) VALUES (a1, a2, a3, ...), (b1, b2, b3, ...),...
-	recordValues := make([]interface{}, 0)
+	// 分表时，每条记录可能被路由到不同的表中，按表名分组后逐表插入
+	var tableNames []string
+	recordValues := make(map[string][]interface{})
 	for _, value := range values {
-		s.CallHoookMethod(BeforeInsert, value)
 		table := s.Model(value).RefTable() // 执行 Parse
-		s.clause.Set(clause.INSERT, table.Name, table.FieldNames)
-		recordValues = append(recordValues, table.RecordValues(value)) // 解析出对象中各个字段的值
+		s.CallHoookMethod(BeforeInsert, value)
+		name := s.TableName(value)
+		if _, ok := recordValues[name]; !ok {
+			tableNames = append(tableNames, name)
+		}
+		recordValues[name] = append(recordValues[name], table.RecordValues(value)) // 解析出对象中各个字段的值
 	}
 
-	s.clause.Set(clause.VALUES, recordValues...)
-	sql, vars := s.clause.Build(clause.INSERT, clause.VALUES)
+	var affected int64
+	for _, name := range tableNames {
+		s.clause.Set(clause.INSERT, name, s.RefTable().FieldNames)
+		s.clause.Set(clause.VALUES, recordValues[name]...)
+		sql, vars := s.clause.Build(clause.INSERT, clause.VALUES)
 
-	result, err := s.Raw(sql, vars...).Exec()
-	if err != nil {
-		return 0, err
+		result, err := s.Raw(sql, vars...).Exec()
+		if err != nil {
+			return affected, err
+		}
+		count, err := result.RowsAffected()
+		if err != nil {
+			return affected, err
+		}
+		affected += count
 	}
 	s.CallHoookMethod(AfterInsert, nil)
-	return result.RowsAffected()
+	return affected, nil
 }
 
 func (s *Session) Find(values interface{}) error {
-	s.CallHoookMethod(BeforeQuery, nil)
 	// var users []User --> Find(&users)
 	destSlice := reflect.Indirect(reflect.ValueOf(values)) // reflect.Value --> []User
 	destType := destSlice.Type().Elem()                    // Array, Chan, Map, Ptr, or Slice reflect.Type --> User
 
 	// reflect.New(destType) --> reflect.Value
-	log.Info(reflect.New(destType).Kind())
-	table := s.Model(reflect.New(destType).Interface()).RefTable()
-
-	s.clause.Set(clause.SELECT, table.Name, table.FieldNames)
-	sql, vars := s.clause.Build(clause.SELECT, clause.WHERE, clause.ORDERBY, clause.LIMIT)
-	rows, err := s.Raw(sql, vars...).QueryRows()
+	rows, err := s.Rows(reflect.New(destType).Interface())
 	if err != nil {
 		return err
 	}
+	defer rows.Close()
 
 	for rows.Next() {
 		dest := reflect.New(destType).Elem()
-		var values []interface{}
-		for _, name := range table.FieldNames {
-			// 向 values 中添加 dest 按序铺平的各个字段指针
-			values = append(values, dest.FieldByName(name).Addr().Interface())
-		}
-		// 依据数据库查询值，为 values 赋值
-		if err := rows.Scan(values...); err != nil {
+		if err := rows.Scan(dest.Addr().Interface()); err != nil {
 			return err
 		}
-		s.CallHoookMethod(AfterQuery, dest.Addr().Interface())
 		destSlice.Set(reflect.Append(destSlice, dest))
 	}
-	return rows.Close()
+	return rows.Err()
+}
+
+// scan 将当前行按列名依次填充到 dest（结构体）对应的字段中，并调用 AfterQuery 钩子
+func (s *Session) scan(rows *sql.Rows, columns []string, dest reflect.Value) error {
+	var values []interface{}
+	for _, name := range columns {
+		// 向 values 中添加 dest 按列名铺平的各个字段指针
+		values = append(values, fieldAddr(dest, name))
+	}
+	// 依据数据库查询值，为 values 赋值
+	if err := rows.Scan(values...); err != nil {
+		return err
+	}
+	s.CallHoookMethod(AfterQuery, dest.Addr().Interface())
+	return nil
+}
+
+// fieldAddr 返回 dest 中与列名对应的字段指针，列名忽略大小写和下划线（user_name --> UserName）
+// 没有对应的字段时，返回一个占位指针丢弃该列的值
+func fieldAddr(dest reflect.Value, column string) interface{} {
+	field := dest.FieldByName(column)
+	if !field.IsValid() {
+		name := strings.ReplaceAll(column, "_", "")
+		field = dest.FieldByNameFunc(func(n string) bool {
+			return strings.EqualFold(n, name)
+		})
+	}
+	if !field.IsValid() || !field.CanSet() {
+		var discard interface{}
+		return &discard
+	}
+	return field.Addr().Interface()
 }
 
 func (s *Session) Update(kv ...interface{}) (int64, error) {
@@ -92,7 +133,7 @@ func (s *Session) Update(kv ...interface{}) (int64, error) {
 		}
 	}
 
-	s.clause.Set(clause.UPDATE, s.RefTable().Name, m)
+	s.clause.Set(clause.UPDATE, s.TableName(s.whereVars()...), m)
 	sql, vars := s.clause.Build(clause.UPDATE, clause.WHERE)
 	result, err := s.Raw(sql, vars...).Exec()
 	if err != nil {
@@ -104,7 +145,7 @@ func (s *Session) Update(kv ...interface{}) (int64, error) {
 
 func (s *Session) Delete() (int64, error) {
 	s.CallHoookMethod(BeforeDelete, nil)
-	s.clause.Set(clause.DELETE, s.RefTable().Name)
+	s.clause.Set(clause.DELETE, s.TableName(s.whereVars()...))
 	sql, vars := s.clause.Build(clause.DELETE, clause.WHERE)
 	result, err := s.Raw(sql, vars...).Exec()
 	if err != nil {
@@ -115,7 +156,7 @@ func (s *Session) Delete() (int64, error) {
 }
 
 func (s *Session) Count() (int64, error) {
-	s.clause.Set(clause.COUNT, s.RefTable().Name)
+	s.clause.Set(clause.COUNT, s.TableName(s.whereVars()...))
 	sql, vars := s.clause.Build(clause.COUNT, clause.WHERE)
 	row := s.Raw(sql, vars...).QueryRow()
 	var tmp int64
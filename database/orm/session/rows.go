@@ -0,0 +1,122 @@
+package session
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/go-examples-with-tests/database/orm/clause"
+)
+
+// Rows 是查询结果的游标，逐行读取数据而不是一次性加载到内存中
+type Rows struct {
+	s       *Session
+	rows    *sql.Rows
+	columns []string
+}
+
+// Rows 依据 value（Model）以及 Where/OrderBy/Limit 构造查询语句，返回结果游标，使用完毕后需要调用 Close
+func (s *Session) Rows(value interface{}) (*Rows, error) {
+	table := s.Model(value).RefTable()
+	s.CallHoookMethod(BeforeQuery, nil)
+
+	s.clause.Set(clause.SELECT, s.TableName(s.whereVars()...), table.FieldNames)
+	sql, vars := s.clause.Build(clause.SELECT, clause.WHERE, clause.ORDERBY, clause.LIMIT)
+	rows, err := s.Raw(sql, vars...).QueryRows()
+	if err != nil {
+		return nil, err
+	}
+	return &Rows{s: s, rows: rows, columns: table.FieldNames}, nil
+}
+
+// Next 移动到下一行，context 被取消时返回 false，此时 Err 返回 context 的错误
+func (r *Rows) Next() bool {
+	return r.rows.Next()
+}
+
+// Scan 将当前行填充到 dest（结构体指针）中，并调用 AfterQuery 钩子
+func (r *Rows) Scan(dest interface{}) error {
+	return r.s.scan(r.rows, r.columns, reflect.Indirect(reflect.ValueOf(dest)))
+}
+
+func (r *Rows) Err() error {
+	return r.rows.Err()
+}
+
+func (r *Rows) Close() error {
+	return r.rows.Close()
+}
+
+var errorType = reflect.TypeOf((*error)(nil)).Elem()
+
+// Each 逐行读取查询结果并调用 f，f 的类型必须是 func(*T) error，T 是 Model 对应的结构体
+// f 返回 error 时停止迭代并返回该 error
+func (s *Session) Each(f interface{}) error {
+	fv := reflect.ValueOf(f)
+	ft := fv.Type()
+	if ft.Kind() != reflect.Func || ft.NumIn() != 1 || ft.NumOut() != 1 ||
+		ft.In(0).Kind() != reflect.Ptr || ft.In(0).Elem().Kind() != reflect.Struct || ft.Out(0) != errorType {
+		return fmt.Errorf("each: want func(*T) error, got %s", ft)
+	}
+	destType := ft.In(0).Elem()
+
+	rows, err := s.Rows(reflect.New(destType).Interface())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		dest := reflect.New(destType)
+		if err := rows.Scan(dest.Interface()); err != nil {
+			return err
+		}
+		if err, _ := fv.Call([]reflect.Value{dest})[0].Interface().(error); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// FindInBatches 每次读取 batchSize 行到 values（*[]T）中并调用 f，batch 从 1 开始计数
+// 每一批都会重新分配切片，f 中可以安全地保留上一批的数据；f 返回 error 时停止迭代
+func (s *Session) FindInBatches(values interface{}, batchSize int, f func(batch int) error) error {
+	if batchSize <= 0 {
+		return errors.New("batch size must be positive")
+	}
+	destSlice := reflect.Indirect(reflect.ValueOf(values))
+	destType := destSlice.Type().Elem()
+
+	rows, err := s.Rows(reflect.New(destType).Interface())
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	batch := 0
+	destSlice.Set(reflect.MakeSlice(destSlice.Type(), 0, batchSize))
+	for rows.Next() {
+		dest := reflect.New(destType).Elem()
+		if err := rows.Scan(dest.Addr().Interface()); err != nil {
+			return err
+		}
+		destSlice.Set(reflect.Append(destSlice, dest))
+
+		if destSlice.Len() == batchSize {
+			batch++
+			if err := f(batch); err != nil {
+				return err
+			}
+			destSlice.Set(reflect.MakeSlice(destSlice.Type(), 0, batchSize))
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if destSlice.Len() > 0 {
+		batch++
+		return f(batch)
+	}
+	return nil
+}
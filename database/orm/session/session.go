@@ -0,0 +1,151 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+
+	"github.com/go-examples-with-tests/database/orm/clause"
+	"github.com/go-examples-with-tests/database/orm/dialect"
+	"github.com/go-examples-with-tests/database/orm/log"
+	"github.com/go-examples-with-tests/database/orm/schema"
+)
+
+type Session struct {
+	db      *sql.DB         // 数据库实例，用于和数据库交互，执行 CRUD 操作
+	ctx     context.Context // 执行 SQL 语句时使用的 context，取消后正在执行的查询和迭代随之结束
+	sql     strings.Builder // SQL 语句
+	sqlVars []interface{}   // SQL 语句中的 ? 占位符对应的参数
+
+	dialect  dialect.Dialect
+	refTable *schema.Schema
+
+	tableName string       // 通过 Table 指定的表名，仅对下一条 SQL 语句生效
+	sharding  ShardingFunc // 分表函数，依据 model 和值计算出实际的表名
+
+	clause clause.Clause
+
+	transaction *sql.Tx
+
+	err error // Raw 绑定命名参数时的错误，在执行 SQL 语句时返回
+}
+
+type CommonDB interface {
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
+	Exec(query string, args ...interface{}) (sql.Result, error)
+
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+func New(db *sql.DB, dialect dialect.Dialect) *Session {
+	return &Session{
+		db:      db,
+		ctx:     context.Background(),
+		dialect: dialect,
+	}
+}
+
+// WithContext 设置之后所有 SQL 语句使用的 context
+func (s *Session) WithContext(ctx context.Context) *Session {
+	s.ctx = ctx
+	return s
+}
+
+func (s *Session) Context() context.Context {
+	return s.ctx
+}
+
+func (s *Session) Clear() {
+	s.sql.Reset()
+	s.sqlVars = nil
+	s.clause = clause.Clause{}
+	s.tableName = ""
+	s.err = nil
+}
+
+func (s *Session) DB() CommonDB {
+	if s.transaction != nil {
+		return s.transaction
+	}
+	return s.db
+}
+
+// Raw 追加 SQL 语句及其参数，参数可以是按序对应 ? 的值，
+// 也可以是一个 map[string]interface{} 或结构体，对应 SQL 语句中的 @name 命名参数
+func (s *Session) Raw(sql string, values ...interface{}) *Session {
+	if isNamedArg(sql, values) {
+		var err error
+		if sql, values, err = bindNamed(sql, values[0]); err != nil {
+			log.Error(err)
+			s.err = err
+			return s
+		}
+	}
+	s.sql.WriteString(sql)
+	s.sql.WriteString(" ")
+	s.sqlVars = append(s.sqlVars, values...)
+	return s
+}
+
+// Exec execs a SQL statement, and return sql.Result
+func (s *Session) Exec() (result sql.Result, err error) {
+	defer s.Clear()
+	if s.err != nil {
+		return nil, s.err
+	}
+	log.Info(s.sql.String(), s.sqlVars)
+	if result, err = s.DB().ExecContext(s.ctx, s.sql.String(), s.sqlVars...); err != nil {
+		log.Error(err)
+	}
+	return
+}
+
+// QueryRow 仅返回一行结果；Raw 绑定命名参数失败时不执行 SQL 语句，错误由返回的 *sql.Row 的 Scan 返回
+func (s *Session) QueryRow() *sql.Row {
+	defer s.Clear()
+	ctx := s.ctx
+	if s.err != nil {
+		ctx = errContext{ctx, s.err}
+	}
+	log.Info(s.sql.String(), s.sqlVars)
+	// 调用的是 sql.DB 的 QueryRow 函数，仅返回一行结果
+	return s.DB().QueryRowContext(ctx, s.sql.String(), s.sqlVars...)
+}
+
+// closedChan 是已经关闭的 channel，作为 errContext 的 Done
+var closedChan = func() chan struct{} {
+	ch := make(chan struct{})
+	close(ch)
+	return ch
+}()
+
+// errContext 是已经结束的 context，Err 返回指定的错误。
+// database/sql 在获取连接之前检查 context，因此 QueryRow 不会执行 SQL 语句，而是返回携带该错误的 *sql.Row
+type errContext struct {
+	context.Context
+	err error
+}
+
+func (c errContext) Done() <-chan struct{} {
+	return closedChan
+}
+
+func (c errContext) Err() error {
+	return c.err
+}
+
+func (s *Session) QueryRows() (rows *sql.Rows, err error) {
+	defer s.Clear()
+	if s.err != nil {
+		return nil, s.err
+	}
+	log.Info(s.sql.String(), s.sqlVars)
+	// 调用的是 sql.DB 的 Query 函数，可返回多行结果
+	if rows, err = s.DB().QueryContext(s.ctx, s.sql.String(), s.sqlVars...); err != nil {
+		log.Error(err)
+	}
+	return
+}
@@ -0,0 +1,430 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/go-examples-with-tests/database/orm/dialect"
+	"github.com/go-examples-with-tests/database/orm/log"
+	_ "github.com/mattn/go-sqlite3"
+)
+
+type User struct {
+	Name string `geeorm:"PRIMARY KEY"`
+}
+
+var (
+	TestDB         *sql.DB
+	TestDialect, _ = dialect.GetDialect("sqlite3")
+)
+
+func TestMain(m *testing.M) {
+	fmt.Println("Main")
+	TestDB, _ = sql.Open("sqlite3", "../../gee.db")
+	code := m.Run()
+	_ = TestDB.Close()
+	os.Exit(code)
+}
+
+func TestSession(t *testing.T) {
+	session := New(TestDB, TestDialect)
+	session.Model(&User{})
+
+	session.DropTable()
+	session.CreateTable()
+	if !session.HasTable() {
+		t.Fatal("create table error!")
+	}
+}
+
+func TestModel(t *testing.T) {
+	session := New(TestDB, TestDialect)
+	session.Model(&User{})
+	table := session.refTable
+
+	session.Model(&Session{})
+
+	if table.Name != "User" || session.refTable.Name != "Session" {
+		t.Fatal("failed to change model")
+	}
+}
+
+func TestExec(t *testing.T) {
+	session := New(TestDB, TestDialect)
+
+	_, _ = session.Raw("DROP TABLE IF EXISTS User;").Exec()
+	_, _ = session.Raw("CREATE TABLE User(Name text);").Exec()
+
+	result, _ := session.Raw("INSERT INTO User('Name') VALUES (?), (?)", "Tom", "Sam").Exec()
+	if count, err := result.RowsAffected(); err != nil || count != 2 {
+		t.Fatal("expect 2, but got:", count)
+	}
+}
+
+type Person struct {
+	Name string
+	Age  int8
+}
+
+func TestInsert(t *testing.T) {
+	session := New(TestDB, TestDialect)
+
+	session.Model(&Person{})
+	if !session.HasTable() {
+		session.CreateTable()
+	} else {
+		session.DropTable()
+		session.CreateTable()
+	}
+
+	if session.HasTable() {
+		p1 := &Person{
+			Name: "Katyusha",
+			Age:  31,
+		}
+		p2 := &Person{
+			Name: "Sam",
+			Age:  32,
+		}
+		p3 := &Person{
+			Name: "Jason",
+			Age:  33,
+		}
+
+		count, err := session.Insert(p1, p2, p3)
+		if err != nil || count != 3 {
+			t.Fatal("insert action error")
+		}
+	}
+}
+
+func TestFind(t *testing.T) {
+	session := New(TestDB, TestDialect)
+	session.Model(&User{})
+
+	var users []User
+
+	if err := session.Find(&users); err != nil || len(users) != 2 {
+		t.Fatal("find error")
+	} else {
+		for i := 0; i < len(users); i++ {
+			fmt.Println(users[i])
+		}
+	}
+}
+
+func TestUpdate(t *testing.T) {
+	session := New(TestDB, TestDialect)
+
+	session.Model(&User{})
+
+	if session.HasTable() {
+		session.DropTable()
+	}
+	session.CreateTable()
+
+	count, err := session.Insert(&User{
+		Name: "Tom",
+	})
+	if err != nil || count != 1 {
+		t.Fatal("insert error")
+	}
+
+	count, err = session.Where("Name = ?", "Tom").Update("Name", "Katyusha")
+	if err != nil || count != 1 {
+		t.Fatal("update error")
+	}
+}
+
+func TestCount(t *testing.T) {
+	session := New(TestDB, TestDialect)
+	session.Model(&User{})
+
+	count, err := session.Count()
+	if err != nil {
+		t.Fatal(err)
+	}
+	log.Infof("count=%d", count)
+	if count == 1 {
+		user := &User{}
+		err := session.First(user)
+		if err != nil {
+			t.Fatal("first error")
+		}
+		log.Info(user.Name)
+	}
+}
+
+type Account struct {
+	ID       int `geeorm:"PRIMARY KEY"`
+	Password string
+}
+
+func (a *Account) BeforeInsert(s *Session) error {
+	log.Info("before insert ", a)
+	a.ID += 100
+	return nil
+}
+
+func (a *Account) AfterQuery(s *Session) error {
+	log.Info("after query:", a)
+	a.Password = "******"
+	return nil
+}
+
+func TestHook(t *testing.T) {
+	session := New(TestDB, TestDialect)
+
+	session.Model(&Account{})
+
+	if session.HasTable() {
+		session.DropTable()
+	}
+	session.CreateTable()
+
+	session.Insert(&Account{1, "123456"}, &Account{2, "qwerty"})
+
+	u := &Account{}
+	err := session.First(u)
+	if err != nil || u.ID != 101 || u.Password != "******" {
+		t.Fatal("Failed to call hooks after query, got:", u)
+	}
+}
+
+type Log struct {
+	Month   string
+	Content string
+}
+
+func TestTable(t *testing.T) {
+	session := New(TestDB, TestDialect)
+	session.Model(&Log{})
+
+	_ = session.Table("Logs_202610").DropTable()
+	if err := session.Table("Logs_202610").CreateTable(); err != nil {
+		t.Fatal(err)
+	}
+	if !session.Table("Logs_202610").HasTable() {
+		t.Fatal("failed to create table Logs_202610")
+	}
+
+	count, err := session.Table("Logs_202610").Insert(&Log{"202610", "hello"})
+	if err != nil || count != 1 {
+		t.Fatal("failed to insert into Logs_202610")
+	}
+
+	var logs []Log
+	if err := session.Table("Logs_202610").Find(&logs); err != nil || len(logs) != 1 {
+		t.Fatal("failed to find from Logs_202610, got:", logs)
+	}
+	// Table 仅对一条 SQL 语句生效
+	if session.TableName() != "Log" {
+		t.Fatal("table name should be reset after query, got:", session.TableName())
+	}
+}
+
+func TestSharding(t *testing.T) {
+	session := New(TestDB, TestDialect)
+	session.Model(&Log{}).Sharding(func(model interface{}, values ...interface{}) string {
+		if len(values) == 0 {
+			return ""
+		}
+		switch v := values[0].(type) {
+		case *Log:
+			return "Logs_" + v.Month
+		case string:
+			return "Logs_" + v
+		}
+		return ""
+	})
+
+	for _, month := range []string{"202609", "202610"} {
+		_ = session.Table("Logs_" + month).DropTable()
+		_ = session.Table("Logs_" + month).CreateTable()
+	}
+
+	count, err := session.Insert(&Log{"202609", "a"}, &Log{"202610", "b"}, &Log{"202610", "c"})
+	if err != nil || count != 3 {
+		t.Fatal("failed to insert into shards, got:", count, err)
+	}
+
+	var logs []Log
+	if err := session.Where("Month = ?", "202610").Find(&logs); err != nil || len(logs) != 2 {
+		t.Fatal("failed to find from shard Logs_202610, got:", logs)
+	}
+	if count, _ := session.Where("Month = ?", "202609").Count(); count != 1 {
+		t.Fatal("failed to count shard Logs_202609, got:", count)
+	}
+}
+
+func TestBindNamed(t *testing.T) {
+	sql, vars, err := bindNamed("SELECT * FROM User WHERE Name = @name AND Note = '@skip' AND Age > @Age",
+		map[string]interface{}{"name": "Tom", "Age": 18})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sql != "SELECT * FROM User WHERE Name = ? AND Note = '@skip' AND Age > ?" {
+		t.Fatal("failed to bind named sql, got:", sql)
+	}
+	if len(vars) != 2 || vars[0] != "Tom" || vars[1] != 18 {
+		t.Fatal("failed to bind named vars, got:", vars)
+	}
+
+	if _, _, err := bindNamed("SELECT * FROM User WHERE Name = @missing", map[string]interface{}{}); err == nil {
+		t.Fatal("expect error for missing named parameter")
+	}
+}
+
+type Report struct {
+	Name  string
+	Total int
+}
+
+func TestRawScan(t *testing.T) {
+	session := New(TestDB, TestDialect)
+	session.Model(&Person{})
+	_ = session.DropTable()
+	_ = session.CreateTable()
+	_, _ = session.Insert(&Person{"Tom", 18}, &Person{"Tom", 20}, &Person{"Sam", 30})
+
+	var reports []Report
+	err := session.Raw("SELECT Name, count(*) AS total FROM Person WHERE Age < @age GROUP BY Name ORDER BY Name",
+		map[string]interface{}{"age": 25}).Scan(&reports)
+	if err != nil || len(reports) != 1 || reports[0].Name != "Tom" || reports[0].Total != 2 {
+		t.Fatal("failed to scan reports with map parameters, got:", reports, err)
+	}
+
+	report := &Report{}
+	err = session.Raw("SELECT Name, Age AS Total FROM Person WHERE Name = @Name", Person{Name: "Sam"}).Scan(report)
+	if err != nil || report.Total != 30 {
+		t.Fatal("failed to scan report with struct parameters, got:", report, err)
+	}
+
+	if err := session.Raw("SELECT Name FROM Person WHERE Name = @nobody", map[string]interface{}{}).Scan(&reports); err == nil {
+		t.Fatal("expect error for missing named parameter")
+	}
+}
+
+func TestQueryRowNamed(t *testing.T) {
+	session := New(TestDB, TestDialect)
+	session.Model(&Person{})
+	_ = session.DropTable()
+	_ = session.CreateTable()
+	_, _ = session.Insert(&Person{"Tom", 18})
+
+	var age int
+	row := session.Raw("SELECT Age FROM Person WHERE Name = @name", map[string]interface{}{"name": "Tom"}).QueryRow()
+	if err := row.Scan(&age); err != nil || age != 18 {
+		t.Fatal("failed to query row with named parameters, got:", age, err)
+	}
+
+	row = session.Raw("SELECT Age FROM Person WHERE Name = @missing", map[string]interface{}{}).QueryRow()
+	if err := row.Scan(&age); err == nil || !strings.Contains(err.Error(), "@missing") {
+		t.Fatal("expect error for missing named parameter, got:", err)
+	}
+
+	// 错误只对上一次 QueryRow 有效
+	if err := session.Raw("SELECT Age FROM Person WHERE Name = ?", "Tom").QueryRow().Scan(&age); err != nil {
+		t.Fatal("expect error to be cleared by next QueryRow, got:", err)
+	}
+}
+
+func initPersons(t *testing.T, n int) *Session {
+	t.Helper()
+	session := New(TestDB, TestDialect)
+	session.Model(&Person{})
+	_ = session.DropTable()
+	_ = session.CreateTable()
+
+	var persons []interface{}
+	for i := 0; i < n; i++ {
+		persons = append(persons, &Person{Name: fmt.Sprintf("p%d", i), Age: int8(i)})
+	}
+	if _, err := session.Insert(persons...); err != nil {
+		t.Fatal(err)
+	}
+	return session
+}
+
+func TestEach(t *testing.T) {
+	session := initPersons(t, 5)
+
+	var names []string
+	err := session.Where("Age >= ?", 2).OrderBy("Age").Each(func(p *Person) error {
+		names = append(names, p.Name)
+		return nil
+	})
+	if err != nil || len(names) != 3 || names[0] != "p2" {
+		t.Fatal("failed to iterate persons, got:", names, err)
+	}
+
+	stop := errors.New("stop")
+	count := 0
+	err = session.Each(func(p *Person) error {
+		count++
+		return stop
+	})
+	if err != stop || count != 1 {
+		t.Fatal("Each should stop at the first error, got:", count, err)
+	}
+
+	if err := session.Each(func(p Person) {}); err == nil {
+		t.Fatal("expect error for invalid callback")
+	}
+}
+
+func TestEachHook(t *testing.T) {
+	session := New(TestDB, TestDialect)
+	session.Model(&Account{})
+	_ = session.DropTable()
+	_ = session.CreateTable()
+	_, _ = session.Insert(&Account{1, "123456"}, &Account{2, "qwerty"})
+
+	err := session.Each(func(a *Account) error {
+		if a.Password != "******" {
+			return fmt.Errorf("AfterQuery should be called for %d", a.ID)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestFindInBatches(t *testing.T) {
+	session := initPersons(t, 7)
+
+	var batch []Person
+	var sizes []int
+	err := session.OrderBy("Age").FindInBatches(&batch, 3, func(n int) error {
+		if n == 1 && batch[0].Name != "p0" {
+			return fmt.Errorf("unexpected first row %v", batch[0])
+		}
+		sizes = append(sizes, len(batch))
+		return nil
+	})
+	if err != nil || !reflect.DeepEqual(sizes, []int{3, 3, 1}) {
+		t.Fatal("failed to find in batches, got:", sizes, err)
+	}
+}
+
+func TestRowsContext(t *testing.T) {
+	session := initPersons(t, 5)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	count := 0
+	err := session.WithContext(ctx).Each(func(p *Person) error {
+		count++
+		cancel()
+		return nil
+	})
+	if err != context.Canceled || count != 1 {
+		t.Fatal("iteration should stop after cancel, got:", count, err)
+	}
+}
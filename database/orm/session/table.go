@@ -0,0 +1,85 @@
+package session
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/go-examples-with-tests/database/orm/log"
+	"github.com/go-examples-with-tests/database/orm/schema"
+)
+
+// ShardingFunc 依据 model 和本次操作涉及的值计算出分表的表名，返回 "" 时使用默认表名
+// Insert 时 values 是待插入的记录；Find/Update/Delete/Count 时 values 是 Where 的参数
+type ShardingFunc func(model interface{}, values ...interface{}) string
+
+func (s *Session) Model(value interface{}) *Session {
+	if s.refTable == nil || reflect.TypeOf(value) != reflect.TypeOf(s.refTable.Model) {
+		s.refTable = schema.Parse(value, s.dialect)
+	}
+	return s
+}
+
+// Schema 直接指定 Session 操作的表结构，而不是从 Model 中解析
+func (s *Session) Schema(table *schema.Schema) *Session {
+	s.refTable = table
+	return s
+}
+
+func (s *Session) RefTable() *schema.Schema {
+	if s.refTable == nil {
+		log.Error("Model is not set")
+	}
+	return s.refTable
+}
+
+// Table 指定下一条 SQL 语句使用的表名，例如按月分表时的 Logs_202610
+func (s *Session) Table(name string) *Session {
+	s.tableName = name
+	return s
+}
+
+// Sharding 设置分表函数，Table 指定的表名优先级更高
+func (s *Session) Sharding(f ShardingFunc) *Session {
+	s.sharding = f
+	return s
+}
+
+// TableName 返回下一条 SQL 语句实际操作的表名：Table > Sharding > schema.Name
+func (s *Session) TableName(values ...interface{}) string {
+	if s.tableName != "" {
+		return s.tableName
+	}
+	table := s.RefTable()
+	if s.sharding != nil {
+		if name := s.sharding(table.Model, values...); name != "" {
+			return name
+		}
+	}
+	return table.Name
+}
+
+func (s *Session) CreateTable() error {
+	table := s.RefTable()
+	var columns []string
+	for _, field := range table.Fields {
+		columns = append(columns, fmt.Sprintf("%s %s %s", field.Name, field.Type, field.Tag))
+	}
+	desc := strings.Join(columns, ",")
+	_, err := s.Raw(fmt.Sprintf("CREATE TABLE %s (%s);", s.TableName(), desc)).Exec()
+	return err
+}
+
+func (s *Session) DropTable() error {
+	_, err := s.Raw(fmt.Sprintf("DROP TABLE IF EXISTS %s", s.TableName())).Exec()
+	return err
+}
+
+func (s *Session) HasTable() bool {
+	name := s.TableName()
+	sql, values := s.dialect.TableExistSQLStmt(name)
+	row := s.Raw(sql, values...).QueryRow()
+	var tmp string
+	_ = row.Scan(&tmp)
+	return tmp == name
+}
@@ -1,10 +1,10 @@
 package session
 
-import "github.com/go-examples-with-tests/database/v3/log"
+import "github.com/go-examples-with-tests/database/orm/log"
 
 func (s *Session) Begin() (err error) {
 	log.Info("transactioin begin")
-	if s.transaction, err = s.db.Begin(); err != nil {
+	if s.transaction, err = s.db.BeginTx(s.ctx, nil); err != nil {
 		log.Error(err)
 		return
 	}
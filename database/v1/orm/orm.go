@@ -1,39 +1,23 @@
 package orm
 
 import (
-	"database/sql"
-
-	"github.com/go-examples-with-tests/database/v1/log"
+	"github.com/go-examples-with-tests/database/orm"
 	"github.com/go-examples-with-tests/database/v1/session"
 )
 
+// 实现已统一迁移到 database/orm，NewSession 仍然返回 v1 的 Session
 type Engine struct {
-	db *sql.DB
+	*orm.Engine
 }
 
-func NewEngine(driver, source string) (e *Engine, err error) {
-	db, err := sql.Open(driver, source)
+func NewEngine(driver, source string) (*Engine, error) {
+	engine, err := orm.NewEngine(driver, source)
 	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	if err = db.Ping(); err != nil {
-		log.Error(err)
-		return
-	}
-	e = &Engine{db: db}
-	log.Info("Connect database success")
-	return
-}
-
-func (engine *Engine) Close() {
-	if err := engine.db.Close(); err != nil {
-		log.Error("Failed to close database")
+		return nil, err
 	}
-	log.Info("Close database success")
+	return &Engine{Engine: engine}, nil
 }
 
 func (engine *Engine) NewSession() *session.Session {
-	return session.New(engine.db)
+	return session.New(engine.DB())
 }
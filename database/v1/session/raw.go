@@ -2,24 +2,23 @@ package session
 
 import (
 	"database/sql"
-	"strings"
 
-	"github.com/go-examples-with-tests/database/v1/log"
+	"github.com/go-examples-with-tests/database/orm/session"
 )
 
+// Session 复用 database/orm 的实现，只保留 v1 原有的方法：Raw、Exec、QueryRow 和 QueryRows。
+// v1 没有 dialect，Model、CreateTable 等依赖 dialect 的方法不对外暴露
 type Session struct {
-	db      *sql.DB         // 数据库实例，用于和数据库交互，执行 CRUD 操作
-	sql     strings.Builder // SQL 语句
-	sqlVars []interface{}   // SQL 语句中的 ? 占位符对应的参数
+	s  *session.Session
+	db *sql.DB
 }
 
 func New(db *sql.DB) *Session {
-	return &Session{db: db}
+	return &Session{s: session.New(db, nil), db: db}
 }
 
 func (s *Session) Clear() {
-	s.sql.Reset()
-	s.sqlVars = nil
+	s.s.Clear()
 }
 
 func (s *Session) DB() *sql.DB {
@@ -27,35 +26,19 @@ func (s *Session) DB() *sql.DB {
 }
 
 func (s *Session) Raw(sql string, values ...interface{}) *Session {
-	s.sql.WriteString(sql)
-	s.sql.WriteString(" ")
-	s.sqlVars = append(s.sqlVars, values...)
+	s.s.Raw(sql, values...)
 	return s
 }
 
 // Exec execs a SQL statement, and return sq.Result
-func (s *Session) Exec() (result sql.Result, err error) {
-	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	if result, err = s.DB().Exec(s.sql.String(), s.sqlVars...); err != nil {
-		log.Error(err)
-	}
-	return
+func (s *Session) Exec() (sql.Result, error) {
+	return s.s.Exec()
 }
 
 func (s *Session) QueryRow() *sql.Row {
-	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	// 调用的是 sql.DB 的 QueryRow 函数，仅返回一行结果
-	return s.DB().QueryRow(s.sql.String(), s.sqlVars...)
-}
-
-func (s *Session) QueryRows() (rows *sql.Rows, err error) {
-	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	// 调用的是 sql.DB 的 Query 函数，可返回多行结果
-	if rows, err = s.DB().Query(s.sql.String(), s.sqlVars...); err != nil {
-		log.Error(err)
-	}
-	return
+	return s.s.QueryRow()
+}
+
+func (s *Session) QueryRows() (*sql.Rows, error) {
+	return s.s.QueryRows()
 }
@@ -1,41 +1,29 @@
 package log
 
 import (
-	"io/ioutil"
-	"log"
-	"os"
-	"sync"
-)
+	"io"
 
-var (
-	errorLog = log.New(os.Stdout, "\033[31m[error]\033[0m", log.LstdFlags|log.Lshortfile)
-	infoLog  = log.New(os.Stdout, "\033[32m[info ]\033[0m", log.LstdFlags|log.Lshortfile)
-	loggers  = []*log.Logger{errorLog, infoLog}
-	mu       sync.Mutex
+	"github.com/go-examples-with-tests/database/orm/log"
 )
 
 // log method
 var (
-	Error  = errorLog.Println
-	Errorf = errorLog.Printf
-	Info   = infoLog.Println
-	Infof  = infoLog.Printf
+	Error  = log.Error
+	Errorf = log.Errorf
+	Info   = log.Info
+	Infof  = log.Infof
 )
 
 const (
-	InfoLevel = iota
-	ErrorLevel
-	Disable
+	InfoLevel  = log.InfoLevel
+	ErrorLevel = log.ErrorLevel
+	Disable    = log.Disable
 )
 
 func SetLevel(level int) {
-	mu.Lock()
-	defer mu.Unlock()
+	log.SetLevel(level)
+}
 
-	if ErrorLevel < level {
-		errorLog.SetOutput(ioutil.Discard)
-	}
-	if InfoLevel < level {
-		infoLog.SetOutput(ioutil.Discard)
-	}
+func SetOutput(w io.Writer) {
+	log.SetOutput(w)
 }
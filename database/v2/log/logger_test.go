@@ -1,18 +1,33 @@
 package log
 
 import (
-	"io/ioutil"
+	"bytes"
+	"strings"
 	"testing"
 )
 
 func TestLogger(t *testing.T) {
+	var buf bytes.Buffer
+	SetOutput(&buf)
+	Info("info message")
+	Error("error message")
+	if !strings.Contains(buf.String(), "info message") || !strings.Contains(buf.String(), "error message") {
+		t.Fatal("expect both info and error messages, got:", buf.String())
+	}
+
 	SetLevel(ErrorLevel)
-	if errorLog.Writer() == ioutil.Discard || infoLog.Writer() != ioutil.Discard {
-		t.Fatal("errorLog's writer must be ioutil.Discard and infoLog's writer must not be ioutil.Discard")
+	buf.Reset()
+	Info("info message")
+	Error("error message")
+	if strings.Contains(buf.String(), "info message") || !strings.Contains(buf.String(), "error message") {
+		t.Fatal("expect only error message, got:", buf.String())
 	}
 
 	SetLevel(Disable)
-	if errorLog.Writer() != ioutil.Discard || infoLog.Writer() != ioutil.Discard {
-		t.Fatal("errorLog and infoLog's writer must be ioutil.Discard")
+	buf.Reset()
+	Info("info message")
+	Error("error message")
+	if buf.Len() != 0 {
+		t.Fatal("expect no message, got:", buf.String())
 	}
 }
@@ -1,48 +1,26 @@
 package orm
 
 import (
-	"database/sql"
-
-	"github.com/go-examples-with-tests/database/v2/dialect"
-	"github.com/go-examples-with-tests/database/v2/log"
+	"github.com/go-examples-with-tests/database/orm"
 	"github.com/go-examples-with-tests/database/v2/session"
 )
 
+type TxFunc = orm.TxFunc
+
+// 实现已统一迁移到 database/orm，NewSession 仍然返回 v2 的 Session
 type Engine struct {
-	db      *sql.DB
-	dialect dialect.Dialect
+	*orm.Engine
 }
 
-func NewEngine(driver, source string) (e *Engine, err error) {
-	db, err := sql.Open(driver, source)
+func NewEngine(driver, source string) (*Engine, error) {
+	engine, err := orm.NewEngine(driver, source)
 	if err != nil {
-		log.Error(err)
-		return
-	}
-
-	if err = db.Ping(); err != nil {
-		log.Error(err)
-		return
-	}
-
-	dial, ok := dialect.GetDialect(driver)
-	if !ok {
-		log.Errorf("get dialect: %s error", driver)
-		return
-	}
-
-	e = &Engine{db: db, dialect: dial}
-	log.Info("Connect database success")
-	return
-}
-
-func (engine *Engine) Close() {
-	if err := engine.db.Close(); err != nil {
-		log.Error("Failed to close database")
+		return nil, err
 	}
-	log.Info("Close database success")
+	return &Engine{Engine: engine}, nil
 }
 
+// NewSession 包装 orm.Engine 创建的 Session，与 database/orm 一样使用 Engine 的分表函数
 func (engine *Engine) NewSession() *session.Session {
-	return session.New(engine.db, engine.dialect)
+	return session.Wrap(engine.Engine.NewSession(), engine.DB())
 }
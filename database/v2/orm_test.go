@@ -1,6 +1,7 @@
 package orm
 
 import (
+	"path/filepath"
 	"testing"
 
 	_ "github.com/mattn/go-sqlite3"
@@ -20,3 +21,22 @@ func TestORM(t *testing.T) {
 	engine := OpenDb(t)
 	defer engine.Close()
 }
+
+type Item struct {
+	Name string
+}
+
+func TestNewSessionSharding(t *testing.T) {
+	engine, err := NewEngine("sqlite3", filepath.Join(t.TempDir(), "shard.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer engine.Close()
+
+	engine.Sharding(func(model interface{}, values ...interface{}) string {
+		return "Item_shard"
+	})
+	if name := engine.NewSession().Model(&Item{}).TableName(); name != "Item_shard" {
+		t.Fatal("v2 session should use the sharding function of engine, got:", name)
+	}
+}
@@ -19,7 +19,7 @@ func TestSchema(t *testing.T) {
 	if userSchema.Name != "User" && len(userSchema.Fields) != 2 {
 		t.Fatal("schema parse User error")
 	}
-	if userSchema.fieldMap["Name"].Tag != "PRIMARY KEY" {
+	if userSchema.GetField("Name").Tag != "PRIMARY KEY" {
 		t.Fatal("schema parse User error")
 	}
 }
@@ -2,34 +2,41 @@ package session
 
 import (
 	"database/sql"
-	"fmt"
-	"reflect"
-	"strings"
 
-	"github.com/go-examples-with-tests/database/v1/log"
-	"github.com/go-examples-with-tests/database/v2/dialect"
-	"github.com/go-examples-with-tests/database/v2/schema"
+	"github.com/go-examples-with-tests/database/orm/dialect"
+	"github.com/go-examples-with-tests/database/orm/session"
 )
 
-type Session struct {
-	db      *sql.DB         // 数据库实例，用于和数据库交互，执行 CRUD 操作
-	sql     strings.Builder // SQL 语句
-	sqlVars []interface{}   // SQL 语句中的 ? 占位符对应的参数
+type (
+	CommonDB     = session.CommonDB
+	Rows         = session.Rows
+	ShardingFunc = session.ShardingFunc
+)
 
-	dialect  dialect.Dialect
-	refTable *schema.Schema
+const (
+	BeforeQuery  = session.BeforeQuery
+	AfterQuery   = session.AfterQuery
+	BeforeUpdate = session.BeforeUpdate
+	AfterUpdate  = session.AfterUpdate
+	BeforeDelete = session.BeforeDelete
+	AfterDelete  = session.AfterDelete
+	BeforeInsert = session.BeforeInsert
+	AfterInsert  = session.AfterInsert
+)
+
+// Session 复用 database/orm 的实现，保留 v2 原有的方法签名：DB 返回 *sql.DB，Raw 和 Model 返回 v2 的 Session
+type Session struct {
+	*session.Session
+	db *sql.DB
 }
 
-func New(db *sql.DB, dialect dialect.Dialect) *Session {
-	return &Session{
-		db:      db,
-		dialect: dialect,
-	}
+func New(db *sql.DB, d dialect.Dialect) *Session {
+	return &Session{Session: session.New(db, d), db: db}
 }
 
-func (s *Session) Clear() {
-	s.sql.Reset()
-	s.sqlVars = nil
+// Wrap 包装 database/orm 创建的 Session，保留其分表函数等设置
+func Wrap(s *session.Session, db *sql.DB) *Session {
+	return &Session{Session: s, db: db}
 }
 
 func (s *Session) DB() *sql.DB {
@@ -37,76 +44,11 @@ func (s *Session) DB() *sql.DB {
 }
 
 func (s *Session) Raw(sql string, values ...interface{}) *Session {
-	s.sql.WriteString(sql)
-	s.sql.WriteString(" ")
-	s.sqlVars = append(s.sqlVars, values...)
+	s.Session.Raw(sql, values...)
 	return s
 }
 
-// Exec execs a SQL statement, and return sq.Result
-func (s *Session) Exec() (result sql.Result, err error) {
-	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	if result, err = s.DB().Exec(s.sql.String(), s.sqlVars...); err != nil {
-		log.Error(err)
-	}
-	return
-}
-
-func (s *Session) QueryRow() *sql.Row {
-	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	// 调用的是 sql.DB 的 QueryRow 函数，仅返回一行结果
-	return s.DB().QueryRow(s.sql.String(), s.sqlVars...)
-}
-
-func (s *Session) QueryRows() (rows *sql.Rows, err error) {
-	defer s.Clear()
-	log.Info(s.sql.String, s.sqlVars)
-	// 调用的是 sql.DB 的 Query 函数，可返回多行结果
-	if rows, err = s.DB().Query(s.sql.String(), s.sqlVars...); err != nil {
-		log.Error(err)
-	}
-	return
-}
-
 func (s *Session) Model(value interface{}) *Session {
-	if s.refTable == nil || reflect.TypeOf(value) != reflect.TypeOf(s.refTable.Model) { // 指针值
-		s.refTable = schema.Parse(value, s.dialect)
-	}
+	s.Session.Model(value)
 	return s
 }
-
-func (s *Session) RefTable() *schema.Schema {
-	if s.refTable == nil {
-		log.Error("Model is not set")
-	}
-	return s.refTable
-}
-
-func (s *Session) CreateTable() error {
-	table := s.RefTable()
-	var columns []string
-	for _, field := range table.Fields {
-		columns = append(columns, fmt.Sprintf("%s %s %s", field.Name, field.Type, field.Tag))
-	}
-	desc := strings.Join(columns, ",")
-	_, err := s.Raw(fmt.Sprintf("CREATE TABLE %s (%s);", table.Name, desc)).Exec()
-	return err
-}
-
-func (s *Session) DropTable() error {
-	_, err := s.Raw(fmt.Sprintf("DROP TABLE IF EXISTS %s", s.refTable.Name)).Exec()
-	return err
-}
-
-func (s *Session) HasTable() bool {
-	sql, values := s.dialect.TableExistSQLStmt(s.refTable.Name)
-	log.Infof("HasTable: %s, args:%v", sql, values)
-	row := s.Raw(sql, values...).QueryRow()
-
-	var tmp string
-	_ = row.Scan(&tmp)
-	log.Infof("Query:%s, Got:%s", s.refTable.Name, tmp)
-	return tmp == s.refTable.Name
-}
@@ -41,11 +41,11 @@ func TestSession(t *testing.T) {
 func TestModel(t *testing.T) {
 	session := New(TestDB, TestDialect)
 	session.Model(&User{})
-	table := session.refTable
+	table := session.RefTable()
 
 	session.Model(&Session{})
 
-	if table.Name != "User" || session.refTable.Name != "Session" {
+	if table.Name != "User" || session.RefTable().Name != "Session" {
 		t.Fatal("failed to change model")
 	}
 }
@@ -1,26 +1,13 @@
 package dialect
 
-import (
-	"fmt"
-	"reflect"
-)
+import "github.com/go-examples-with-tests/database/orm/dialect"
 
-var dialectsMap = map[string]Dialect{} // 进程全局保存注册的 name - Dialect
+type Dialect = dialect.Dialect
 
-type Dialect interface {
-	DataTypeOf(typ reflect.Value) string                        // Go-type convert to RDMS-type
-	TableExistSQLStmt(tableName string) (string, []interface{}) // 指定tablename是否存在的SQL语句
+func RegisterDialect(name string, d Dialect) {
+	dialect.RegisterDialect(name, d)
 }
 
-func RegisterDialect(name string, dialect Dialect) {
-	_, ok := GetDialect(name)
-	if ok {
-		panic(fmt.Sprintf("dialect for %s just registe once", name))
-	}
-	dialectsMap[name] = dialect
-}
-
-func GetDialect(name string) (dialect Dialect, ok bool) {
-	dialect, ok = dialectsMap[name]
-	return
+func GetDialect(name string) (Dialect, bool) {
+	return dialect.GetDialect(name)
 }
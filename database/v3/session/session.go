@@ -2,83 +2,29 @@ package session
 
 import (
 	"database/sql"
-	"strings"
 
-	"github.com/go-examples-with-tests/database/v3/clause"
-	"github.com/go-examples-with-tests/database/v3/dialect"
-	"github.com/go-examples-with-tests/database/v3/log"
-	"github.com/go-examples-with-tests/database/v3/schema"
+	"github.com/go-examples-with-tests/database/orm/dialect"
+	"github.com/go-examples-with-tests/database/orm/session"
 )
 
-type Session struct {
-	db      *sql.DB         // 数据库实例，用于和数据库交互，执行 CRUD 操作
-	sql     strings.Builder // SQL 语句
-	sqlVars []interface{}   // SQL 语句中的 ? 占位符对应的参数
-
-	dialect  dialect.Dialect
-	refTable *schema.Schema
-
-	clause clause.Clause
-
-	transaction *sql.Tx
-}
-
-type CommonDB interface {
-	Query(query string, args ...interface{}) (*sql.Rows, error)
-	QueryRow(query string, args ...interface{}) *sql.Row
-	Exec(query string, args ...interface{}) (sql.Result, error)
-}
-
-func New(db *sql.DB, dialect dialect.Dialect) *Session {
-	return &Session{
-		db:      db,
-		dialect: dialect,
-	}
-}
-
-func (s *Session) Clear() {
-	s.sql.Reset()
-	s.sqlVars = nil
-	s.clause = clause.Clause{}
-}
-
-func (s *Session) DB() CommonDB {
-	if s.transaction != nil {
-		return s.transaction
-	}
-	return s.db
-}
-
-func (s *Session) Raw(sql string, values ...interface{}) *Session {
-	s.sql.WriteString(sql)
-	s.sql.WriteString(" ")
-	s.sqlVars = append(s.sqlVars, values...)
-	return s
-}
-
-// Exec execs a SQL statement, and return sql.Result
-func (s *Session) Exec() (result sql.Result, err error) {
-	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	if result, err = s.DB().Exec(s.sql.String(), s.sqlVars...); err != nil {
-		log.Error(err)
-	}
-	return
-}
+type (
+	Session      = session.Session
+	CommonDB     = session.CommonDB
+	Rows         = session.Rows
+	ShardingFunc = session.ShardingFunc
+)
 
-func (s *Session) QueryRow() *sql.Row {
-	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	// 调用的是 sql.DB 的 QueryRow 函数，仅返回一行结果
-	return s.DB().QueryRow(s.sql.String(), s.sqlVars...)
-}
+const (
+	BeforeQuery  = session.BeforeQuery
+	AfterQuery   = session.AfterQuery
+	BeforeUpdate = session.BeforeUpdate
+	AfterUpdate  = session.AfterUpdate
+	BeforeDelete = session.BeforeDelete
+	AfterDelete  = session.AfterDelete
+	BeforeInsert = session.BeforeInsert
+	AfterInsert  = session.AfterInsert
+)
 
-func (s *Session) QueryRows() (rows *sql.Rows, err error) {
-	defer s.Clear()
-	log.Info(s.sql.String(), s.sqlVars)
-	// 调用的是 sql.DB 的 Query 函数，可返回多行结果
-	if rows, err = s.DB().Query(s.sql.String(), s.sqlVars...); err != nil {
-		log.Error(err)
-	}
-	return
+func New(db *sql.DB, d dialect.Dialect) *Session {
+	return session.New(db, d)
 }
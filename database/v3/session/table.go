@@ -1,49 +0,0 @@
-package session
-
-import (
-	"fmt"
-	"reflect"
-	"strings"
-
-	"github.com/go-examples-with-tests/database/v3/log"
-	"github.com/go-examples-with-tests/database/v3/schema"
-)
-
-func (s *Session) Model(value interface{}) *Session {
-	if s.refTable == nil || reflect.TypeOf(value) != reflect.TypeOf(s.refTable.Model) {
-		s.refTable = schema.Parse(value, s.dialect)
-	}
-	return s
-}
-
-func (s *Session) RefTable() *schema.Schema {
-	if s.refTable == nil {
-		log.Error("Model is not set")
-	}
-	return s.refTable
-}
-
-func (s *Session) CreateTable() error {
-	table := s.RefTable()
-	var columns []string
-	for _, field := range table.Fields {
-		columns = append(columns, fmt.Sprintf("%s %s %s", field.Name, field.Type, field.Tag))
-	}
-	desc := strings.Join(columns, ",")
-	_, err := s.Raw(fmt.Sprintf("CREATE TABLE %s (%s);", table.Name, desc)).Exec()
-	return err
-}
-
-func (s *Session) DropTable() error {
-	_, err := s.Raw(fmt.Sprintf("DROP TABLE IF EXISTS %s", s.refTable.Name)).Exec()
-	return err
-}
-
-func (s *Session) HasTable() bool {
-	sql, values := s.dialect.TableExistSQLStmt(s.refTable.Name)
-	row := s.Raw(sql, values...).QueryRow()
-
-	var tmp string
-	_ = row.Scan(&tmp)
-	return tmp == s.refTable.Name
-}
@@ -19,25 +19,32 @@ func main() {
 	}
 	defer conn.Close()
 
-	c := pb.NewCacheClient(conn)
+	c := pb.NewUserServiceClient(conn)
 
 	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
 	defer cancel()
 
-	limit := int64(1)
-	msg := "Katyusha"
-	reply, err := c.ListUsers(ctx, &pb.ListUsersRequest{
-		Limit: &limit,
-		Msg:   &msg,
-	})
+	user, err := c.GetUser(ctx, &pb.GetUserRequest{Name: "admin"})
 	if err != nil {
 		log.Fatal(err)
 	}
-	log.Printf("reply.Count:%d", reply.GetCount())
-	items := reply.GetItems()
-	if items != nil && len(items) > 0 {
-		for _, item := range items {
-			log.Printf("%s, %s, %s, %s", item.Nickname, item.Password, item.Phone, item.Email)
+	log.Printf("%s, %s, %s, %s", user.Name, user.Nickname, user.Phone, user.Email)
+
+	// 按页获取所有用户，直到 next_page_token 为空
+	limit := int64(10)
+	request := &pb.ListUsersRequest{Limit: &limit}
+	for {
+		reply, err := c.ListUsers(ctx, request)
+		if err != nil {
+			log.Fatal(err)
+		}
+		log.Printf("reply.Count:%d", reply.GetCount())
+		for _, item := range reply.GetItems() {
+			log.Printf("%s, %s, %s, %s", item.Name, item.Nickname, item.Phone, item.Email)
+		}
+		if reply.GetNextPageToken() == "" {
+			break
 		}
+		request.PageToken = reply.GetNextPageToken()
 	}
 }
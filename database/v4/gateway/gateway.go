@@ -0,0 +1,258 @@
+// Package gateway 将 /v1/users 的 REST/JSON 请求转换为 pb 中的消息，再调用 gRPC 的 UserService
+package gateway
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-examples-with-tests/database/v4/pb"
+	"github.com/go-examples-with-tests/database/v4/server"
+	"github.com/marmotedu/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+// handlerFunc 将 HTTP 请求转换为 gRPC 调用，返回的消息以 JSON 的形式写回
+type handlerFunc func(c *gin.Context, ctx context.Context, opts ...grpc.CallOption) (proto.Message, error)
+
+// route 描述一个 REST 接口，OpenAPI 文档也由它生成
+type route struct {
+	name     string // 对应的 RPC，同时作为 OpenAPI 中的 operationId
+	method   string
+	path     string
+	summary  string
+	params   []param
+	request  proto.Message // 请求体，为 nil 时没有请求体
+	response proto.Message
+	status   int
+	handler  handlerFunc
+}
+
+type param struct {
+	name     string
+	in       string // path 或 query
+	typ      string
+	required bool
+	desc     string
+}
+
+// errResponse 是出错时返回的 JSON，code 为 pkg/code 中的错误码，status 为 gRPC 的状态码
+type errResponse struct {
+	Code    int    `json:"code,omitempty"`
+	Status  string `json:"status"`
+	Message string `json:"message"`
+}
+
+var marshaler = protojson.MarshalOptions{UseProtoNames: true, EmitUnpopulated: true}
+
+// httpStatus 是没有错误码时，gRPC 状态码到 HTTP 状态码的映射
+var httpStatus = map[codes.Code]int{
+	codes.OK:                 http.StatusOK,
+	codes.Canceled:           499,
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Aborted:            http.StatusConflict,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+}
+
+type Gateway struct {
+	client pb.UserServiceClient
+	routes []route
+	engine *gin.Engine
+}
+
+func New(client pb.UserServiceClient) *Gateway {
+	g := &Gateway{client: client, engine: gin.New()}
+	g.engine.Use(gin.Recovery())
+
+	name := param{name: "name", in: "path", typ: "string", required: true, desc: "用户名"}
+	g.handle(route{name: "CreateUser", method: http.MethodPost, path: "/v1/users", summary: "创建用户",
+		request: &pb.CreateUserRequest{}, response: &pb.UserInfo{}, status: http.StatusCreated, handler: g.createUser})
+	g.handle(route{name: "ListUsers", method: http.MethodGet, path: "/v1/users", summary: "分页查询用户",
+		params: []param{
+			{name: "limit", in: "query", typ: "integer", desc: "每页的最大记录数，默认 20，最大 100"},
+			{name: "page_token", in: "query", typ: "string", desc: "上一页返回的 next_page_token"},
+			{name: "field_selector", in: "query", typ: "string", desc: "例如 name=admin,phone!=123"},
+			{name: "label_selector", in: "query", typ: "string"},
+		}, response: &pb.ListUsersResponse{}, status: http.StatusOK, handler: g.listUsers})
+	g.handle(route{name: "GetUser", method: http.MethodGet, path: "/v1/users/:name", summary: "查询用户",
+		params: []param{name}, response: &pb.UserInfo{}, status: http.StatusOK, handler: g.getUser})
+	g.handle(route{name: "UpdateUser", method: http.MethodPatch, path: "/v1/users/:name", summary: "更新用户",
+		params:  []param{name, {name: "update_mask", in: "query", typ: "string", desc: "逗号分隔的字段名，为空时更新所有可修改的字段"}},
+		request: &pb.UserInfo{}, response: &pb.UserInfo{}, status: http.StatusOK, handler: g.updateUser})
+	g.handle(route{name: "DeleteUser", method: http.MethodDelete, path: "/v1/users/:name", summary: "删除用户",
+		params: []param{name, {name: "unscoped", in: "query", typ: "boolean", desc: "为 true 时从数据库中删除，否则为软删除"}},
+		status: http.StatusNoContent, handler: g.deleteUser})
+	g.handle(route{name: "ChangePassword", method: http.MethodPut, path: "/v1/users/:name/password", summary: "修改密码",
+		params: []param{name}, request: &pb.ChangePasswordRequest{}, status: http.StatusNoContent, handler: g.changePassword})
+	g.handle(route{name: "VerifyPassword", method: http.MethodPost, path: "/v1/users/:name/verify-password", summary: "校验密码",
+		params: []param{name}, request: &pb.VerifyPasswordRequest{}, response: &pb.UserInfo{}, status: http.StatusOK, handler: g.verifyPassword})
+
+	g.engine.GET("/openapi.json", func(c *gin.Context) {
+		c.JSON(http.StatusOK, g.OpenAPI())
+	})
+	return g
+}
+
+func (g *Gateway) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	g.engine.ServeHTTP(w, r)
+}
+
+func (g *Gateway) handle(r route) {
+	g.routes = append(g.routes, r)
+	g.engine.Handle(r.method, r.path, func(c *gin.Context) {
+		// Authorization 和 X-Request-Id 通过 metadata 传递给 gRPC 服务
+		md := metadata.MD{}
+		if auth := c.GetHeader("Authorization"); auth != "" {
+			md.Set("authorization", auth)
+		}
+		if id := c.GetHeader(server.RequestIDKey); id != "" {
+			md.Set(server.RequestIDKey, id)
+			c.Header(server.RequestIDKey, id)
+		}
+		ctx := metadata.NewOutgoingContext(c.Request.Context(), md)
+
+		var header metadata.MD
+		resp, err := r.handler(c, ctx, grpc.Header(&header))
+		if ids := header.Get(server.RequestIDKey); len(ids) > 0 {
+			c.Header(server.RequestIDKey, ids[0])
+		}
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		if r.response == nil {
+			c.Status(r.status)
+			return
+		}
+		data, err := marshaler.Marshal(resp)
+		if err != nil {
+			writeError(c, err)
+			return
+		}
+		c.Data(r.status, "application/json; charset=utf-8", data)
+	})
+}
+
+func writeError(c *gin.Context, err error) {
+	st := status.Convert(err)
+	resp := errResponse{Status: st.Code().String(), Message: st.Message()}
+	httpCode, ok := httpStatus[st.Code()]
+	if !ok {
+		httpCode = http.StatusInternalServerError
+	}
+	// 有错误码时，使用错误码注册时指定的 HTTP 状态码，与 HTTP 服务保持一致
+	if c, ok := server.ErrorCode(err); ok {
+		resp.Code = c
+		httpCode = errors.ParseCoder(errors.WithCode(c, st.Message())).HTTPStatus()
+	}
+	c.AbortWithStatusJSON(httpCode, resp)
+}
+
+// bindJSON 使用 protojson 解析请求体，字段名可以是 proto 中的名字或 JSON 名字
+func bindJSON(c *gin.Context, m proto.Message) error {
+	data, err := ioutil.ReadAll(c.Request.Body)
+	if err != nil {
+		return status.Error(codes.InvalidArgument, err.Error())
+	}
+	if len(data) == 0 {
+		return nil
+	}
+	if err := protojson.Unmarshal(data, m); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+func (g *Gateway) createUser(c *gin.Context, ctx context.Context, opts ...grpc.CallOption) (proto.Message, error) {
+	req := &pb.CreateUserRequest{}
+	if err := bindJSON(c, req); err != nil {
+		return nil, err
+	}
+	return g.client.CreateUser(ctx, req, opts...)
+}
+
+func (g *Gateway) getUser(c *gin.Context, ctx context.Context, opts ...grpc.CallOption) (proto.Message, error) {
+	return g.client.GetUser(ctx, &pb.GetUserRequest{Name: c.Param("name")}, opts...)
+}
+
+func (g *Gateway) updateUser(c *gin.Context, ctx context.Context, opts ...grpc.CallOption) (proto.Message, error) {
+	user := &pb.UserInfo{}
+	if err := bindJSON(c, user); err != nil {
+		return nil, err
+	}
+	user.Name = c.Param("name")
+	req := &pb.UpdateUserRequest{User: user}
+	if mask := c.Query("update_mask"); mask != "" {
+		req.UpdateMask = &fieldmaskpb.FieldMask{Paths: strings.Split(mask, ",")}
+	}
+	return g.client.UpdateUser(ctx, req, opts...)
+}
+
+func (g *Gateway) deleteUser(c *gin.Context, ctx context.Context, opts ...grpc.CallOption) (proto.Message, error) {
+	unscoped, _ := strconv.ParseBool(c.Query("unscoped"))
+	return g.client.DeleteUser(ctx, &pb.DeleteUserRequest{Name: c.Param("name"), Unscoped: unscoped}, opts...)
+}
+
+func (g *Gateway) listUsers(c *gin.Context, ctx context.Context, opts ...grpc.CallOption) (proto.Message, error) {
+	req := &pb.ListUsersRequest{
+		PageToken:     c.Query("page_token"),
+		FieldSelector: c.Query("field_selector"),
+		LabelSelector: c.Query("label_selector"),
+	}
+	if s := c.Query("limit"); s != "" {
+		limit, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, status.Errorf(codes.InvalidArgument, "invalid limit: %s", s)
+		}
+		req.Limit = &limit
+	}
+	return g.client.ListUsers(ctx, req, opts...)
+}
+
+func (g *Gateway) changePassword(c *gin.Context, ctx context.Context, opts ...grpc.CallOption) (proto.Message, error) {
+	req := &pb.ChangePasswordRequest{}
+	if err := bindJSON(c, req); err != nil {
+		return nil, err
+	}
+	req.Name = c.Param("name")
+	return g.client.ChangePassword(ctx, req, opts...)
+}
+
+func (g *Gateway) verifyPassword(c *gin.Context, ctx context.Context, opts ...grpc.CallOption) (proto.Message, error) {
+	req := &pb.VerifyPasswordRequest{}
+	if err := bindJSON(c, req); err != nil {
+		return nil, err
+	}
+	req.Name = c.Param("name")
+	return g.client.VerifyPassword(ctx, req, opts...)
+}
+
+// DialInProcess 通过内存中的 net.Pipe 连接 s，网关的请求仍然经过 s 上注册的拦截器。
+// s.Stop 或 s.GracefulStop 时 listener 会被关闭
+func DialInProcess(s *grpc.Server) (*grpc.ClientConn, error) {
+	listener := newPipeListener()
+	go func() { _ = s.Serve(listener) }()
+	return grpc.DialContext(context.Background(), "inprocess", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}))
+}
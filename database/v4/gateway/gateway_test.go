@@ -0,0 +1,151 @@
+package gateway
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-examples-with-tests/database/v4/pb"
+	"github.com/go-examples-with-tests/database/v4/pkg/code"
+	"github.com/go-examples-with-tests/database/v4/server"
+	"github.com/go-examples-with-tests/database/v4/store/fake"
+	"github.com/marmotedu/errors"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+)
+
+func init() {
+	gin.SetMode(gin.TestMode)
+}
+
+func newGateway(t *testing.T) *Gateway {
+	s := grpc.NewServer(server.NewServerOption().GRPCOptions()...)
+	pb.RegisterUserServiceServer(s, server.NewCache(fake.NewFactory(), 0))
+	conn, err := DialInProcess(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		_ = conn.Close()
+		s.Stop()
+	})
+	return New(pb.NewUserServiceClient(conn))
+}
+
+func do(g *Gateway, method, target, body string) (*httptest.ResponseRecorder, map[string]interface{}) {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(server.RequestIDKey, "req-1")
+	w := httptest.NewRecorder()
+	g.ServeHTTP(w, req)
+	var resp map[string]interface{}
+	_ = json.Unmarshal(w.Body.Bytes(), &resp)
+	return w, resp
+}
+
+func TestGateway(t *testing.T) {
+	g := newGateway(t)
+	create := `{"user": {"name": "tom", "nickname": "tom", "email": "tom@example.com"}, "password": "secret"}`
+	tests := []struct {
+		name   string
+		method string
+		target string
+		body   string
+		status int
+		check  func(resp map[string]interface{}) bool
+	}{
+		{"create", http.MethodPost, "/v1/users", create, http.StatusCreated, func(resp map[string]interface{}) bool {
+			_, hasPassword := resp["password"]
+			return resp["name"] == "tom" && !hasPassword
+		}},
+		{"create duplicate", http.MethodPost, "/v1/users", create, http.StatusConflict, func(resp map[string]interface{}) bool {
+			return resp["code"] == float64(code.ErrUserAlreadyExist) && resp["status"] == "AlreadyExists"
+		}},
+		{"create invalid json", http.MethodPost, "/v1/users", `{"user":`, http.StatusBadRequest, nil},
+		{"get", http.MethodGet, "/v1/users/tom", "", http.StatusOK, func(resp map[string]interface{}) bool {
+			return resp["email"] == "tom@example.com"
+		}},
+		{"update nickname", http.MethodPatch, "/v1/users/tom?update_mask=nickname", `{"nickname": "tommy", "email": "x@example.com"}`,
+			http.StatusOK, func(resp map[string]interface{}) bool {
+				return resp["nickname"] == "tommy" && resp["email"] == "tom@example.com"
+			}},
+		{"update password by mask", http.MethodPatch, "/v1/users/tom?update_mask=password", `{}`, http.StatusBadRequest, nil},
+		{"list", http.MethodGet, "/v1/users?limit=1", "", http.StatusOK, func(resp map[string]interface{}) bool {
+			return resp["count"] == "1" && resp["next_page_token"] == ""
+		}},
+		{"list invalid limit", http.MethodGet, "/v1/users?limit=a", "", http.StatusBadRequest, nil},
+		{"verify wrong password", http.MethodPost, "/v1/users/tom/verify-password", `{"password": "wrong"}`, http.StatusUnauthorized,
+			func(resp map[string]interface{}) bool { return resp["code"] == float64(code.ErrPasswordIncorrect) }},
+		{"change password", http.MethodPut, "/v1/users/tom/password", `{"old_password": "secret", "new_password": "new"}`, http.StatusNoContent, nil},
+		{"verify password", http.MethodPost, "/v1/users/tom/verify-password", `{"password": "new"}`, http.StatusOK, nil},
+		{"delete", http.MethodDelete, "/v1/users/tom", "", http.StatusNoContent, nil},
+		{"get deleted", http.MethodGet, "/v1/users/tom", "", http.StatusNotFound, func(resp map[string]interface{}) bool {
+			return resp["code"] == float64(code.ErrUserNotFound)
+		}},
+	}
+	for _, tt := range tests {
+		w, resp := do(g, tt.method, tt.target, tt.body)
+		if w.Code != tt.status {
+			t.Fatalf("%s: want status %d, got %d: %s", tt.name, tt.status, w.Code, w.Body.String())
+		}
+		if w.Header().Get(server.RequestIDKey) != "req-1" {
+			t.Fatalf("%s: request id should be returned, got: %v", tt.name, w.Header())
+		}
+		if tt.check != nil && !tt.check(resp) {
+			t.Fatalf("%s: unexpected response: %s", tt.name, w.Body.String())
+		}
+	}
+}
+
+// 错误码注册的 HTTP 状态码与对应的 gRPC 状态码在 httpStatus 中的映射一致，
+// 无论响应中是否带有错误码，网关都返回相同的 HTTP 状态码
+func TestHTTPStatusOfCodes(t *testing.T) {
+	for c, grpcCode := range map[int]codes.Code{
+		code.ErrValidation:        codes.InvalidArgument,
+		code.ErrPasswordIncorrect: codes.Unauthenticated,
+		code.ErrUserNotFound:      codes.NotFound,
+		code.ErrUserAlreadyExist:  codes.AlreadyExists,
+		code.ErrUserConflict:      codes.Aborted,
+	} {
+		if want, got := httpStatus[grpcCode], errors.ParseCoder(errors.WithCode(c, "test")).HTTPStatus(); got != want {
+			t.Fatalf("code %d should be registered with HTTP %d like %s, got %d", c, want, grpcCode, got)
+		}
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	g := newGateway(t)
+	w, resp := do(g, http.MethodGet, "/openapi.json", "")
+	if w.Code != http.StatusOK || resp["openapi"] != "3.0.3" {
+		t.Fatal("failed to get openapi, got:", w.Body.String())
+	}
+
+	paths := resp["paths"].(map[string]interface{})
+	for _, r := range g.routes {
+		path := pathParam.ReplaceAllString(r.path, "{$1}")
+		item, ok := paths[path].(map[string]interface{})
+		if !ok || item[strings.ToLower(r.method)] == nil {
+			t.Fatalf("%s %s is missing in openapi", r.method, path)
+		}
+	}
+	schemas := resp["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	user := schemas["UserInfo"].(map[string]interface{})["properties"].(map[string]interface{})
+	if _, ok := user["password"]; ok || user["created_at"] == nil {
+		t.Fatal("unexpected UserInfo schema:", user)
+	}
+}
+
+func TestPipeListenerClose(t *testing.T) {
+	listener := newPipeListener()
+	_ = listener.Close()
+	if _, err := listener.Accept(); err != net.ErrClosed {
+		t.Fatal("accept on closed listener should fail, got:", err)
+	}
+	if _, err := listener.DialContext(context.Background()); err != net.ErrClosed {
+		t.Fatal("dial on closed listener should fail, got:", err)
+	}
+}
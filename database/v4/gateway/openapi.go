@@ -0,0 +1,146 @@
+package gateway
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// pathParam 匹配 gin 路由中的参数，例如 /v1/users/:name --> /v1/users/{name}
+var pathParam = regexp.MustCompile(`:([A-Za-z_]+)`)
+
+// OpenAPI 依据注册的路由和 pb 消息的描述生成 OpenAPI 3.0 文档
+func (g *Gateway) OpenAPI() map[string]interface{} {
+	schemas := map[string]interface{}{
+		"Error": map[string]interface{}{
+			"type": "object",
+			"properties": map[string]interface{}{
+				"code":    map[string]interface{}{"type": "integer", "description": "pkg/code 中定义的错误码"},
+				"status":  map[string]interface{}{"type": "string", "description": "gRPC 状态码"},
+				"message": map[string]interface{}{"type": "string"},
+			},
+		},
+	}
+	paths := map[string]interface{}{}
+	for _, r := range g.routes {
+		path := pathParam.ReplaceAllString(r.path, "{$1}")
+		item, ok := paths[path].(map[string]interface{})
+		if !ok {
+			item = map[string]interface{}{}
+			paths[path] = item
+		}
+
+		op := map[string]interface{}{
+			"summary":     r.summary,
+			"operationId": r.name,
+		}
+		var params []interface{}
+		for _, p := range r.params {
+			params = append(params, map[string]interface{}{
+				"name":        p.name,
+				"in":          p.in,
+				"required":    p.required,
+				"description": p.desc,
+				"schema":      map[string]interface{}{"type": p.typ},
+			})
+		}
+		if params != nil {
+			op["parameters"] = params
+		}
+		if r.request != nil {
+			op["requestBody"] = map[string]interface{}{
+				"required": true,
+				"content":  jsonContent(messageRef(r.request.ProtoReflect().Descriptor(), schemas)),
+			}
+		}
+
+		response := map[string]interface{}{"description": http.StatusText(r.status)}
+		if r.response != nil {
+			response["content"] = jsonContent(messageRef(r.response.ProtoReflect().Descriptor(), schemas))
+		}
+		op["responses"] = map[string]interface{}{
+			strconv.Itoa(r.status): response,
+			"default": map[string]interface{}{
+				"description": "错误",
+				"content":     jsonContent(map[string]interface{}{"$ref": "#/components/schemas/Error"}),
+			},
+		}
+		item[strings.ToLower(r.method)] = op
+	}
+
+	return map[string]interface{}{
+		"openapi":    "3.0.3",
+		"info":       map[string]interface{}{"title": "UserService", "version": "v1"},
+		"paths":      paths,
+		"components": map[string]interface{}{"schemas": schemas},
+	}
+}
+
+func jsonContent(schema interface{}) map[string]interface{} {
+	return map[string]interface{}{"application/json": map[string]interface{}{"schema": schema}}
+}
+
+// messageRef 将 md 加入 schemas，返回对它的引用，字段名与 protojson 的 UseProtoNames 一致
+func messageRef(md protoreflect.MessageDescriptor, schemas map[string]interface{}) map[string]interface{} {
+	if schema, ok := wellKnownSchema(md); ok {
+		return schema
+	}
+	name := string(md.Name())
+	ref := map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	if _, ok := schemas[name]; ok {
+		return ref
+	}
+
+	properties := map[string]interface{}{}
+	schemas[name] = map[string]interface{}{"type": "object", "properties": properties}
+	fields := md.Fields()
+	for i := 0; i < fields.Len(); i++ {
+		fd := fields.Get(i)
+		schema := fieldSchema(fd, schemas)
+		if fd.IsList() {
+			schema = map[string]interface{}{"type": "array", "items": schema}
+		}
+		properties[string(fd.Name())] = schema
+	}
+	return ref
+}
+
+func fieldSchema(fd protoreflect.FieldDescriptor, schemas map[string]interface{}) map[string]interface{} {
+	switch fd.Kind() {
+	case protoreflect.MessageKind, protoreflect.GroupKind:
+		return messageRef(fd.Message(), schemas)
+	case protoreflect.BoolKind:
+		return map[string]interface{}{"type": "boolean"}
+	case protoreflect.Int32Kind, protoreflect.Sint32Kind, protoreflect.Sfixed32Kind,
+		protoreflect.Uint32Kind, protoreflect.Fixed32Kind:
+		return map[string]interface{}{"type": "integer", "format": "int32"}
+	case protoreflect.Int64Kind, protoreflect.Sint64Kind, protoreflect.Sfixed64Kind,
+		protoreflect.Uint64Kind, protoreflect.Fixed64Kind:
+		// protojson 将 64 位整数编码为字符串
+		return map[string]interface{}{"type": "string", "format": "int64"}
+	case protoreflect.FloatKind, protoreflect.DoubleKind:
+		return map[string]interface{}{"type": "number"}
+	case protoreflect.BytesKind:
+		return map[string]interface{}{"type": "string", "format": "byte"}
+	case protoreflect.EnumKind:
+		return map[string]interface{}{"type": "string"}
+	default:
+		return map[string]interface{}{"type": "string"}
+	}
+}
+
+// wellKnownSchema 返回 protojson 中有特殊编码的消息的 schema
+func wellKnownSchema(md protoreflect.MessageDescriptor) (map[string]interface{}, bool) {
+	switch md.FullName() {
+	case "google.protobuf.Timestamp":
+		return map[string]interface{}{"type": "string", "format": "date-time"}, true
+	case "google.protobuf.FieldMask":
+		return map[string]interface{}{"type": "string", "description": "逗号分隔的字段名"}, true
+	case "google.protobuf.Empty":
+		return map[string]interface{}{"type": "object"}, true
+	}
+	return nil, false
+}
@@ -1,14 +1,25 @@
 package main
 
 import (
+	"context"
+	"fmt"
 	"log"
 	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
 
+	"github.com/go-examples-with-tests/database/v4/gateway"
 	"github.com/go-examples-with-tests/database/v4/pb"
 	"github.com/go-examples-with-tests/database/v4/pkg"
 	"github.com/go-examples-with-tests/database/v4/server"
 	"github.com/go-examples-with-tests/database/v4/store"
 
+	"github.com/spf13/pflag"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/reflection"
 )
@@ -20,49 +31,146 @@ func main() {
 	log.Println("hello, world!")
 
 	options := pkg.NewMySQLOptions()
-	initOptions(options)
+	serverConf := server.NewServerOption()
+	if err := loadConfig(os.Args[1:], options, serverConf); err != nil {
+		log.Fatal(err)
+	}
 	log.Printf("MariaDB options:%s", options)
 
 	// connection to MariaDB
-	dbFactory, err := store.GetMySQLFactoryOr(options)
-	// close DB connection
-	defer dbFactory.Close()
+	dbFactory, err := store.NewMySQLFactory(options)
 	if err != nil {
 		log.Fatal(err)
 	}
-	store.SetClient(dbFactory)
 
-	serverConf := server.NewServerOption()
-	opts := []grpc.ServerOption{grpc.MaxRecvMsgSize(100)}
-	grpcServer := grpc.NewServer(opts...)
+	grpcServer := grpc.NewServer(serverConf.GRPCOptions()...)
+
+	// 写操作产生的变更事件通过 WatchUsers 推送给订阅者
+	events := store.NewBroadcaster(serverConf.WatchHistory, serverConf.WatchBuffer)
+	cache := server.NewCache(store.WithEvents(dbFactory, events), serverConf.CacheBytes)
+	pb.RegisterUserServiceServer(grpcServer, cache)
+	checker := server.NewHealthChecker(dbFactory, serverConf.HealthCheckInterval)
+	checker.Register(grpcServer)
+	reflection.Register(grpcServer)
 
-	cache, err := server.GetCacheInsOr(dbFactory)
+	// 收到 SIGINT 或 SIGTERM 后 ctx 结束，Run 依次关闭网关和 gRPC 服务后返回
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	// 缓存的命中情况通过只监听本机的管理接口查看，不暴露在网关上
+	admin := http.NewServeMux()
+	admin.Handle("/debug/cache", cache.StatsHandler())
+	err = Run(ctx, serverConf, grpcServer, checker, events, admin)
+	stop()
 	if err != nil {
 		log.Println(err.Error())
-		return
 	}
-	pb.RegisterCacheServer(grpcServer, cache)
-	reflection.Register(grpcServer)
 
-	Run(serverConf, grpcServer)
+	// 所有的请求都处理完之后再关闭数据库连接
+	if err := dbFactory.Close(); err != nil {
+		log.Printf("failed to close db: %s", err.Error())
+	}
+	log.Println("server exited")
 }
 
-func Run(opts *server.ServerOption, server *grpc.Server) {
+// Run 启动 gRPC 服务，配置了 HTTPPort 时同时启动 REST 网关，网关通过进程内的连接调用 gRPC 服务；
+// 配置了 AdminAddr 时在该地址上提供 admin。
+// ctx 结束或任一服务出错时，先将健康状态置为 NOT_SERVING 并结束所有的 WatchUsers，再依次关闭 HTTP 服务和 gRPC 服务
+func Run(ctx context.Context, opts *server.ServerOption, grpcServer *grpc.Server, checker *server.HealthChecker,
+	events *store.Broadcaster, admin http.Handler) error {
 	listener, err := net.Listen("tcp", opts.Addr+opts.Port)
 	if err != nil {
-		log.Fatalf("failed to listen: %s", listener.Addr())
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		log.Printf("grpc server listening on %s", listener.Addr())
+		return grpcServer.Serve(listener)
+	})
+	eg.Go(func() error {
+		checker.Run(ctx)
+		return nil
+	})
+
+	var httpServers []*http.Server
+	serveHTTP := func(name string, srv *http.Server) {
+		httpServers = append(httpServers, srv)
+		eg.Go(func() error {
+			log.Printf("%s listening on %s", name, srv.Addr)
+			if err := srv.ListenAndServe(); err != http.ErrServerClosed {
+				return err
+			}
+			return nil
+		})
+	}
+	if opts.HTTPPort != "" {
+		conn, err := gateway.DialInProcess(grpcServer)
+		if err != nil {
+			// 已经启动的 gRPC 服务和健康检查需要结束之后再返回
+			cancel()
+			grpcServer.Stop()
+			_ = eg.Wait()
+			return fmt.Errorf("failed to dial grpc server: %w", err)
+		}
+		defer conn.Close()
+		gw := gateway.New(pb.NewUserServiceClient(conn))
+		serveHTTP("http gateway", &http.Server{Addr: opts.Addr + opts.HTTPPort, Handler: gw})
 	}
-	if err := server.Serve(listener); err != nil {
-		log.Fatalf("failed to start grpc server: %s", err.Error())
+	if opts.AdminAddr != "" {
+		serveHTTP("admin server", &http.Server{Addr: opts.AdminAddr, Handler: admin})
 	}
+
+	eg.Go(func() error {
+		<-ctx.Done()
+		log.Println("shutting down server...")
+		checker.Shutdown()
+		// WatchUsers 不会自己结束，需要先关闭，否则 GracefulStop 会一直等到超时
+		events.Close()
+
+		// HTTP 服务和 gRPC 服务共用同一个关闭期限
+		deadline := time.Now().Add(opts.ShutdownTimeout)
+		shutdownCtx, cancel := context.WithDeadline(context.Background(), deadline)
+		defer cancel()
+		for _, srv := range httpServers {
+			if err := srv.Shutdown(shutdownCtx); err != nil {
+				log.Printf("failed to shutdown http server on %s: %s", srv.Addr, err.Error())
+			}
+		}
+		if !server.GracefulStop(grpcServer, time.Until(deadline)) {
+			log.Println("grpc server is forcibly stopped")
+		}
+		return nil
+	})
+
+	return eg.Wait()
+}
+
+// config 对应 iam-apiserver.yaml 中的配置
+type config struct {
+	MySQL  *pkg.MySQLOptions    `mapstructure:"mysql"`
+	Server *server.ServerOption `mapstructure:"server"`
 }
 
-func initOptions(options *pkg.MySQLOptions) {
-	if options == nil {
-		return
+// loadConfig 依次从配置文件、环境变量和命令行参数中加载配置，并检查配置是否合法
+func loadConfig(args []string, options *pkg.MySQLOptions, serverConf *server.ServerOption) error {
+	fs := pflag.NewFlagSet("database", pflag.ExitOnError)
+	configFile := fs.StringP("config", "c", "iam-apiserver.yaml", "Read configuration from specified `FILE`.")
+	options.AddFlags(fs)
+	serverConf.AddFlags(fs)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if err := pkg.ReadYAMLConfig(*configFile, fs, &config{MySQL: options, Server: serverConf}); err != nil {
+		return err
+	}
+
+	var errs []string
+	for _, err := range append(options.Validate(), serverConf.Validate()...) {
+		errs = append(errs, err.Error())
+	}
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid config: %s", strings.Join(errs, "; "))
 	}
-	options.Username = "iam"
-	options.Password = "iam59!z$"
-	options.Database = "iam"
-	options.LogLevel = 4
+	return nil
 }
@@ -0,0 +1,72 @@
+package code
+
+import (
+	"net/http"
+
+	"github.com/marmotedu/errors"
+)
+
+// 错误码的定义参考 IAM：1 位服务 + 2 位模块 + 3 位序号，10 表示通用错误，11 表示 apiserver 服务
+const (
+	// ErrValidation - 400: Validation failed.
+	ErrValidation = 100004
+
+	// ErrDatabase - 500: Database error.
+	ErrDatabase = 100101
+
+	// ErrEncrypt - 500: Error occurred while encrypting the user password.
+	ErrEncrypt = 100201
+
+	// ErrPasswordIncorrect - 401: Password was incorrect.
+	ErrPasswordIncorrect = 100206
+
+	// ErrUserNotFound - 404: User not found.
+	ErrUserNotFound = 110001
+
+	// ErrUserAlreadyExist - 409: User already exist.
+	ErrUserAlreadyExist = 110002
+
+	// ErrUserConflict - 409: User has been modified by others.
+	ErrUserConflict = 110003
+)
+
+// ErrCode 实现 errors.Coder 接口，将错误码和 HTTP 状态码、对外的错误信息关联起来
+type ErrCode struct {
+	C    int
+	HTTP int
+	Ext  string
+	Ref  string
+}
+
+func (coder ErrCode) Code() int {
+	return coder.C
+}
+
+func (coder ErrCode) String() string {
+	return coder.Ext
+}
+
+func (coder ErrCode) Reference() string {
+	return coder.Ref
+}
+
+func (coder ErrCode) HTTPStatus() int {
+	if coder.HTTP == 0 {
+		return http.StatusInternalServerError
+	}
+	return coder.HTTP
+}
+
+func register(code int, httpStatus int, message string) {
+	errors.MustRegister(&ErrCode{C: code, HTTP: httpStatus, Ext: message})
+}
+
+func init() {
+	register(ErrValidation, http.StatusBadRequest, "Validation failed")
+	register(ErrDatabase, http.StatusInternalServerError, "Database error")
+	register(ErrEncrypt, http.StatusInternalServerError, "Error occurred while encrypting the user password")
+	register(ErrPasswordIncorrect, http.StatusUnauthorized, "Password was incorrect")
+	register(ErrUserNotFound, http.StatusNotFound, "User not found")
+	register(ErrUserAlreadyExist, http.StatusConflict, "User already exist")
+	register(ErrUserConflict, http.StatusConflict, "User has been modified by others")
+}
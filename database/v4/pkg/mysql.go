@@ -25,7 +25,8 @@ type Options struct {
 // New create a new gorm db instance with the given options.
 func New(opts *Options) (*gorm.DB, error) {
 	// DSN Data Source Name: username:password@protocol(address)/dbname?param=value
-	dsn := fmt.Sprintf(`%s:%s@tcp(%s)/%s?charset=utf8&parseTime=%t&loc=%s`,
+	// clientFoundRows: UPDATE 返回匹配的行数而不是实际修改的行数，乐观锁依据它判断记录是否被修改过
+	dsn := fmt.Sprintf(`%s:%s@tcp(%s)/%s?charset=utf8&parseTime=%t&loc=%s&clientFoundRows=true`,
 		opts.Username,
 		opts.Password,
 		opts.Host,
@@ -3,6 +3,8 @@ package pkg
 import (
 	"fmt"
 	"time"
+
+	"github.com/spf13/pflag"
 )
 
 // MySQLOptions defines options for mysql database.
@@ -30,6 +32,54 @@ func NewMySQLOptions() *MySQLOptions {
 	}
 }
 
+// AddFlags 将 MySQL 相关的命令行参数添加到 fs 中，参数名与配置文件中的 key 一致，例如 --mysql.host
+func (options *MySQLOptions) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&options.Host, "mysql.host", options.Host, "MySQL service host address.")
+	fs.StringVar(&options.Username, "mysql.username", options.Username, "Username for access to mysql service.")
+	fs.StringVar(&options.Password, "mysql.password", options.Password, "Password for access to mysql.")
+	fs.StringVar(&options.Database, "mysql.database", options.Database, "Database name for the server to use.")
+	fs.IntVar(&options.MaxIdleConnections, "mysql.max-idle-connections", options.MaxIdleConnections,
+		"Maximum idle connections allowed to connect to mysql.")
+	fs.IntVar(&options.MaxOpenConnections, "mysql.max-open-connections", options.MaxOpenConnections,
+		"Maximum open connections allowed to connect to mysql.")
+	fs.DurationVar(&options.MaxConnectionLifeTime, "mysql.max-connection-life-time", options.MaxConnectionLifeTime,
+		"Maximum connection life time allowed to connect to mysql.")
+	fs.IntVar(&options.LogLevel, "mysql.log-level", options.LogLevel,
+		"Specify gorm log level, 1: silent, 2: error, 3: warn, 4: info.")
+}
+
+// Validate 检查配置是否合法，返回所有不合法的配置项
+func (options *MySQLOptions) Validate() []error {
+	var errs []error
+	if options.Host == "" {
+		errs = append(errs, fmt.Errorf("mysql.host can not be empty"))
+	}
+	if options.Username == "" {
+		errs = append(errs, fmt.Errorf("mysql.username can not be empty"))
+	}
+	if options.Database == "" {
+		errs = append(errs, fmt.Errorf("mysql.database can not be empty"))
+	}
+	if options.MaxIdleConnections < 0 {
+		errs = append(errs, fmt.Errorf("mysql.max-idle-connections %d must not be negative", options.MaxIdleConnections))
+	}
+	if options.MaxOpenConnections < 0 {
+		errs = append(errs, fmt.Errorf("mysql.max-open-connections %d must not be negative", options.MaxOpenConnections))
+	}
+	if options.MaxConnectionLifeTime < 0 {
+		errs = append(errs, fmt.Errorf("mysql.max-connection-life-time %s must not be negative", options.MaxConnectionLifeTime))
+	}
+	if options.LogLevel < 1 || options.LogLevel > 4 {
+		errs = append(errs, fmt.Errorf("mysql.log-level %d must be in [1, 4]", options.LogLevel))
+	}
+	return errs
+}
+
+// String 用于打印配置，密码不会以明文输出
 func (options *MySQLOptions) String() string {
-	return fmt.Sprintf("Host:%s, Username:%s, Password:%s, Database:%s, MaxIdleConnections:%d, MaxOpenConnections:%d, MaxConnectionLifeTime:%v, LogLevel:%d", options.Host, options.Username, options.Password, options.Database, options.MaxIdleConnections, options.MaxOpenConnections, options.MaxConnectionLifeTime, options.LogLevel)
+	password := ""
+	if options.Password != "" {
+		password = "******"
+	}
+	return fmt.Sprintf("Host:%s, Username:%s, Password:%s, Database:%s, MaxIdleConnections:%d, MaxOpenConnections:%d, MaxConnectionLifeTime:%v, LogLevel:%d", options.Host, options.Username, password, options.Database, options.MaxIdleConnections, options.MaxOpenConnections, options.MaxConnectionLifeTime, options.LogLevel)
 }
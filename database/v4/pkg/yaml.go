@@ -1,7 +1,36 @@
 package pkg
 
-func ReadYAMLConfig() (*MySQLOptions, error) {
-	// input yaml configuration file --> opts *pkg.MySQLOptions
+import (
+	"strings"
 
-	return nil, nil
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+)
+
+// EnvPrefix 是环境变量的前缀，配置项中的 . 和 - 替换为 _，例如 mysql.max-idle-connections 对应 IAM_MYSQL_MAX_IDLE_CONNECTIONS
+const EnvPrefix = "IAM"
+
+// ReadYAMLConfig 将配置读取到 config 中，config 的字段使用 mapstructure 标签对应配置文件中的 key。
+// 优先级从高到低依次为：命令行中指定的参数、环境变量、YAML 配置文件、config 中原有的默认值
+func ReadYAMLConfig(file string, fs *pflag.FlagSet, config interface{}) error {
+	v := viper.New()
+	v.SetEnvPrefix(EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer(".", "_", "-", "_"))
+	v.AutomaticEnv()
+
+	if file != "" {
+		v.SetConfigFile(file)
+		if err := v.ReadInConfig(); err != nil {
+			return err
+		}
+	}
+	// 绑定命令行参数后，viper 才知道有哪些配置项，未出现在配置文件中的配置项也可以通过环境变量设置
+	if fs != nil {
+		if err := v.BindPFlags(fs); err != nil {
+			return err
+		}
+	}
+
+	// 使用 Unmarshal 而不是 UnmarshalKey，后者不会合并环境变量和命令行参数
+	return v.Unmarshal(config)
 }
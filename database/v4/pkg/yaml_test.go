@@ -0,0 +1,75 @@
+package pkg
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
+type testConfig struct {
+	MySQL *MySQLOptions `mapstructure:"mysql"`
+}
+
+func TestReadYAMLConfig(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	content := `
+mysql:
+  host: 10.0.0.1:3306
+  username: iam
+  password: secret
+  database: iam
+  max-connection-life-time: 30s
+  log-level: 2
+`
+	if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	os.Setenv("IAM_MYSQL_USERNAME", "env")
+	os.Setenv("IAM_MYSQL_MAX_IDLE_CONNECTIONS", "5")
+	os.Setenv("IAM_MYSQL_DATABASE", "env")
+	defer os.Unsetenv("IAM_MYSQL_USERNAME")
+	defer os.Unsetenv("IAM_MYSQL_MAX_IDLE_CONNECTIONS")
+	defer os.Unsetenv("IAM_MYSQL_DATABASE")
+
+	options := NewMySQLOptions()
+	fs := pflag.NewFlagSet("test", pflag.ContinueOnError)
+	options.AddFlags(fs)
+	if err := fs.Parse([]string{"--mysql.database=flag"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := ReadYAMLConfig(file, fs, &testConfig{MySQL: options}); err != nil {
+		t.Fatal(err)
+	}
+
+	if options.Host != "10.0.0.1:3306" || options.Password != "secret" || options.LogLevel != 2 {
+		t.Fatal("failed to read yaml config, got:", options)
+	}
+	if options.MaxConnectionLifeTime != 30*time.Second {
+		t.Fatal("failed to parse duration, got:", options.MaxConnectionLifeTime)
+	}
+	if options.Username != "env" || options.MaxIdleConnections != 5 {
+		t.Fatal("environment variables should override yaml config, got:", options)
+	}
+	if options.Database != "flag" {
+		t.Fatal("flags should override environment variables, got:", options.Database)
+	}
+	if options.MaxOpenConnections != 100 {
+		t.Fatal("default value should be kept, got:", options.MaxOpenConnections)
+	}
+}
+
+func TestMySQLOptions(t *testing.T) {
+	options := NewMySQLOptions()
+	options.Password = "iam59!z$"
+	if strings.Contains(options.String(), options.Password) {
+		t.Fatal("password should be masked, got:", options.String())
+	}
+	if errs := options.Validate(); len(errs) != 2 {
+		t.Fatal("username and database are required, got:", errs)
+	}
+}
@@ -2,61 +2,343 @@ package server
 
 import (
 	"context"
+	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"log"
-	"sync"
+	"net/http"
+	"strconv"
 
 	"github.com/go-examples-with-tests/database/v4/pb"
+	"github.com/go-examples-with-tests/database/v4/pkg/code"
 	"github.com/go-examples-with-tests/database/v4/store"
+	geecache "github.com/go-examples-with-tests/net/http/v4"
 
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	"github.com/marmotedu/component-base/pkg/auth"
 	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+	"github.com/marmotedu/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/emptypb"
+	"google.golang.org/protobuf/types/known/timestamppb"
 )
 
+// defaultPageSize 是 ListUsers 未指定 limit 时每页的记录数，maxPageSize 是 limit 的上限
+const (
+	defaultPageSize = 20
+	maxPageSize     = 100
+)
+
+// Cache 实现了 pb.UserServiceServer，用户数据保存在 store 中，
+// GetUser 查询到的用户以用户名为 key、protobuf 编码的 UserInfo 为值缓存在 geecache 中。
+// 写操作之后删除缓存，geecache 会丢弃删除之前开始的加载结果，旧数据不会重新写入缓存
 type Cache struct {
 	store store.Factory
+	users *geecache.Group
 }
 
-var (
-	cacheServer *Cache
-	once        sync.Once
-)
+// NewCache 创建 Cache，cacheBytes 为缓存的最大字节数，0 表示不限制
+func NewCache(sf store.Factory, cacheBytes int64) *Cache {
+	cache := &Cache{store: sf}
+	// 每个 Cache 使用独立的 Group，不注册到 geecache 的全局 groups 中，Cache 不再使用时可以被回收
+	cache.users = geecache.NewLocalGroup("users", cacheBytes, geecache.GetterFunc(cache.load))
+	return cache
+}
 
-func GetCacheInsOr(sf store.Factory) (*Cache, error) {
-	if sf != nil {
-		once.Do(func() {
-			cacheServer = &Cache{store: sf}
-		})
+// load 是缓存未命中时的回调，geecache 的 Getter 不能传递 context，这里使用 context.Background()
+func (cache *Cache) load(name string) ([]byte, error) {
+	user, err := cache.store.Users().Get(context.Background(), name, metav1.GetOptions{})
+	if err != nil {
+		return nil, err
 	}
+	return proto.Marshal(toUserInfo(user))
+}
 
-	if cacheServer == nil {
-		return nil, fmt.Errorf("got nil cache server")
+// Stats 返回缓存的命中情况
+func (cache *Cache) Stats() geecache.Stats {
+	return cache.users.Stats()
+}
+
+// StatsHandler 以 JSON 格式返回 Stats，只应该注册在管理接口上
+func (cache *Cache) StatsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(cache.Stats())
+	})
+}
+
+func (cache *Cache) CreateUser(ctx context.Context, request *pb.CreateUserRequest) (*pb.UserInfo, error) {
+	info := request.GetUser()
+	if info.GetName() == "" || info.GetNickname() == "" || request.GetPassword() == "" || info.GetEmail() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name, nickname, password and email are required")
 	}
-	return cacheServer, nil
+
+	user := &v1.User{
+		ObjectMeta: metav1.ObjectMeta{Name: info.Name},
+		Nickname:   info.Nickname,
+		Password:   request.Password,
+		Email:      info.Email,
+		Phone:      info.Phone,
+		IsAdmin:    int(info.IsAdmin),
+	}
+	if err := cache.store.Users().Create(ctx, user, metav1.CreateOptions{}); err != nil {
+		return nil, toStatus(err)
+	}
+	return toUserInfo(user), nil
 }
 
-func (cache *Cache) ListUsers(ctx context.Context, request *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
-	limit, msg := request.Limit, request.Msg
-	log.Printf("get Request! limit:%d, msg:%s", *limit, *msg)
+func (cache *Cache) GetUser(ctx context.Context, request *pb.GetUserRequest) (*pb.UserInfo, error) {
+	if request.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	view, err := cache.users.Get(request.GetName())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	info := &pb.UserInfo{}
+	if err := proto.Unmarshal(view.ByteSlice(), info); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return info, nil
+}
 
-	// CRUD，拿着 userStore 就可以和 MariaDB 交互
+func (cache *Cache) UpdateUser(ctx context.Context, request *pb.UpdateUserRequest) (*pb.UserInfo, error) {
+	info := request.GetUser()
+	paths := request.GetUpdateMask().GetPaths()
+	if len(paths) == 0 {
+		paths = []string{"nickname", "email", "phone", "is_admin"}
+	}
+	for _, path := range paths {
+		if _, ok := userSetters[path]; !ok {
+			return nil, status.Errorf(codes.InvalidArgument, "field %q can not be updated", path)
+		}
+	}
+
+	// 先读取当前的用户，store 依据读到的版本（updatedAt）实现乐观锁；
+	// 客户端传入 updated_at 时，它必须是客户端读到的版本，否则其他客户端在此之后的修改会被覆盖
 	userStore := cache.store.Users()
-	user, err := userStore.Get(context.TODO(), "admin", metav1.GetOptions{})
+	user, err := userStore.Get(ctx, info.GetName(), metav1.GetOptions{})
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	if info.UpdatedAt != nil {
+		if err := info.UpdatedAt.CheckValid(); err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		if expected := info.UpdatedAt.AsTime(); !expected.Equal(user.UpdatedAt) {
+			return nil, toStatus(errors.WithCode(code.ErrUserConflict, "user %s has been modified since %s", user.Name, expected))
+		}
+	}
+	for _, path := range paths {
+		userSetters[path](user, info)
+	}
+
+	err = userStore.Update(ctx, user, metav1.UpdateOptions{})
+	// 无论更新是否成功都删除缓存，例如乐观锁冲突说明缓存中的数据可能已经过期
+	cache.users.Remove(user.Name)
 	if err != nil {
-		log.Println(err.Error())
-	} else {
-		log.Println(user)
+		return nil, toStatus(err)
 	}
+	return toUserInfo(user), nil
+}
+
+// DeleteUser 删除不存在（或者已经软删除）的用户时返回 NotFound
+func (cache *Cache) DeleteUser(ctx context.Context, request *pb.DeleteUserRequest) (*emptypb.Empty, error) {
+	if request.GetName() == "" {
+		return nil, status.Error(codes.InvalidArgument, "name is required")
+	}
+	err := cache.store.Users().Delete(ctx, request.GetName(), metav1.DeleteOptions{Unscoped: request.GetUnscoped()})
+	cache.users.Remove(request.GetName())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
 
-	items := make([]*pb.UserInfo, 0)
-	items = append(items, &pb.UserInfo{
-		Nickname: user.Name,
-		Password: user.Password,
-		Phone:    user.Phone,
-		Email:    user.Email,
+func (cache *Cache) ListUsers(ctx context.Context, request *pb.ListUsersRequest) (*pb.ListUsersResponse, error) {
+	limit := int64(defaultPageSize)
+	if request.Limit != nil {
+		if *request.Limit <= 0 {
+			return nil, status.Errorf(codes.InvalidArgument, "limit %d must be positive", *request.Limit)
+		}
+		// 超过上限时按上限返回，客户端通过 next_page_token 继续获取
+		limit = *request.Limit
+		if limit > maxPageSize {
+			limit = maxPageSize
+		}
+	}
+	offset, err := decodePageToken(request.GetPageToken())
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	log.Printf("list users, offset:%d, limit:%d", offset, limit)
+
+	// CRUD，拿着 userStore 就可以和 MariaDB 交互
+	users, err := cache.store.Users().List(ctx, metav1.ListOptions{
+		FieldSelector: request.GetFieldSelector(),
+		LabelSelector: request.GetLabelSelector(),
+		Offset:        &offset,
+		Limit:         &limit,
 	})
+	if err != nil {
+		return nil, toStatus(err)
+	}
 
-	return &pb.ListUsersResponse{
-		Count: 10,
+	// 查询到的用户不写入缓存：List 期间并发的 UpdateUser 或 DeleteUser 可能已经删除了缓存，
+	// 此时写入的是旧数据，并且不会再失效
+	items := make([]*pb.UserInfo, 0, len(users.Items))
+	for _, user := range users.Items {
+		items = append(items, toUserInfo(user))
+	}
+
+	response := &pb.ListUsersResponse{
+		Count: users.TotalCount,
 		Items: items,
-	}, nil
+	}
+	if next := offset + int64(len(items)); len(items) > 0 && next < users.TotalCount {
+		response.NextPageToken = encodePageToken(next)
+	}
+	return response, nil
+}
+
+func (cache *Cache) ChangePassword(ctx context.Context, request *pb.ChangePasswordRequest) (*emptypb.Empty, error) {
+	if request.GetNewPassword() == "" {
+		return nil, status.Error(codes.InvalidArgument, "new password is required")
+	}
+	if _, err := cache.verifyPassword(ctx, request.GetName(), request.GetOldPassword()); err != nil {
+		return nil, toStatus(err)
+	}
+	err := cache.store.Users().ChangePassword(ctx, request.GetName(), request.GetNewPassword(), metav1.UpdateOptions{})
+	// 修改密码会更新 updatedAt
+	cache.users.Remove(request.GetName())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return &emptypb.Empty{}, nil
+}
+
+func (cache *Cache) VerifyPassword(ctx context.Context, request *pb.VerifyPasswordRequest) (*pb.UserInfo, error) {
+	user, err := cache.verifyPassword(ctx, request.GetName(), request.GetPassword())
+	if err != nil {
+		return nil, toStatus(err)
+	}
+	return toUserInfo(user), nil
+}
+
+// dummyPassword 是与 store 加密密码时相同 cost 的 bcrypt 哈希，用户不存在时用它来比较密码
+const dummyPassword = "$2a$10$a97fFIqpXBYrkuA7Q4mInuuiQD4UsBVG9Up9BJp7mskBGdUB0.WmK"
+
+// verifyPassword 校验用户的密码，用户不存在时同样返回 ErrPasswordIncorrect，
+// 并且同样执行一次 bcrypt 比较，避免通过错误信息或者响应时间泄露用户是否存在
+func (cache *Cache) verifyPassword(ctx context.Context, username, password string) (*v1.User, error) {
+	user, err := cache.store.Users().Get(ctx, username, metav1.GetOptions{})
+	if err != nil {
+		if errors.IsCode(err, code.ErrUserNotFound) {
+			_ = auth.Compare(dummyPassword, password)
+			return nil, errors.WithCode(code.ErrPasswordIncorrect, "user %s not found", username)
+		}
+		return nil, err
+	}
+	if err := user.Compare(password); err != nil {
+		return nil, errors.WithCode(code.ErrPasswordIncorrect, err.Error())
+	}
+	return user, nil
+}
+
+func (cache *Cache) WatchUsers(request *pb.WatchUsersRequest, stream pb.UserService_WatchUsersServer) error {
+	source, ok := cache.store.(store.EventSource)
+	if !ok {
+		return status.Error(codes.Unimplemented, "store does not produce events")
+	}
+	sub, err := source.Events().Subscribe(request.GetStartRevision())
+	if err != nil {
+		return watchStatus(err)
+	}
+	defer sub.Close()
+
+	for {
+		select {
+		case <-stream.Context().Done():
+			return status.FromContextError(stream.Context().Err()).Err()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return watchStatus(sub.Err())
+			}
+			if err := stream.Send(toUserEvent(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// watchStatus 将订阅结束的原因转换为 gRPC 的 status
+func watchStatus(err error) error {
+	switch err {
+	case nil:
+		return nil
+	case store.ErrCompacted:
+		return status.Error(codes.OutOfRange, err.Error())
+	case store.ErrSlowConsumer:
+		return status.Error(codes.ResourceExhausted, err.Error())
+	case store.ErrBroadcasterClosed:
+		return status.Error(codes.Unavailable, "server is shutting down")
+	default:
+		return status.Error(codes.Internal, err.Error())
+	}
+}
+
+var eventTypes = map[store.EventType]pb.UserEvent_Type{
+	store.EventCreated: pb.UserEvent_CREATED,
+	store.EventUpdated: pb.UserEvent_UPDATED,
+	store.EventDeleted: pb.UserEvent_DELETED,
+}
+
+func toUserEvent(event store.Event) *pb.UserEvent {
+	return &pb.UserEvent{
+		Type:     eventTypes[event.Type],
+		Revision: event.Revision,
+		User:     toUserInfo(event.User),
+	}
+}
+
+// userSetters 是 UpdateUser 中 update_mask 可以指定的字段
+var userSetters = map[string]func(user *v1.User, info *pb.UserInfo){
+	"nickname": func(user *v1.User, info *pb.UserInfo) { user.Nickname = info.GetNickname() },
+	"email":    func(user *v1.User, info *pb.UserInfo) { user.Email = info.GetEmail() },
+	"phone":    func(user *v1.User, info *pb.UserInfo) { user.Phone = info.GetPhone() },
+	"is_admin": func(user *v1.User, info *pb.UserInfo) { user.IsAdmin = int(info.GetIsAdmin()) },
+}
+
+func toUserInfo(user *v1.User) *pb.UserInfo {
+	return &pb.UserInfo{
+		Name:      user.Name,
+		Nickname:  user.Nickname,
+		Phone:     user.Phone,
+		Email:     user.Email,
+		IsAdmin:   int64(user.IsAdmin),
+		CreatedAt: timestamppb.New(user.CreatedAt),
+		UpdatedAt: timestamppb.New(user.UpdatedAt),
+	}
+}
+
+// page token 对调用方是不透明的，内部是下一页的 offset
+func encodePageToken(offset int64) string {
+	return base64.RawURLEncoding.EncodeToString([]byte(strconv.FormatInt(offset, 10)))
+}
+
+func decodePageToken(token string) (int64, error) {
+	if token == "" {
+		return 0, nil
+	}
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return 0, fmt.Errorf("invalid page token: %s", token)
+	}
+	offset, err := strconv.ParseInt(string(data), 10, 64)
+	if err != nil || offset < 0 {
+		return 0, fmt.Errorf("invalid page token: %s", token)
+	}
+	return offset, nil
 }
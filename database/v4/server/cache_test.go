@@ -0,0 +1,387 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-examples-with-tests/database/v4/pb"
+	"github.com/go-examples-with-tests/database/v4/pkg/code"
+	"github.com/go-examples-with-tests/database/v4/store"
+	"github.com/go-examples-with-tests/database/v4/store/fake"
+	geecache "github.com/go-examples-with-tests/net/http/v4"
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	"github.com/marmotedu/component-base/pkg/auth"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+	"github.com/marmotedu/errors"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestPageToken(t *testing.T) {
+	offset, err := decodePageToken(encodePageToken(40))
+	if err != nil || offset != 40 {
+		t.Fatalf("page token round trip failed, got: %d, %v", offset, err)
+	}
+	if offset, err := decodePageToken(""); err != nil || offset != 0 {
+		t.Fatalf("empty page token should start from 0, got: %d, %v", offset, err)
+	}
+	if _, err := decodePageToken("not a token"); err == nil {
+		t.Fatal("invalid page token should fail")
+	}
+}
+
+func TestToStatus(t *testing.T) {
+	tests := map[int]codes.Code{
+		code.ErrUserNotFound:      codes.NotFound,
+		code.ErrUserAlreadyExist:  codes.AlreadyExists,
+		code.ErrUserConflict:      codes.Aborted,
+		code.ErrValidation:        codes.InvalidArgument,
+		code.ErrDatabase:          codes.Internal,
+		code.ErrPasswordIncorrect: codes.Unauthenticated,
+	}
+	for c, want := range tests {
+		err := toStatus(errors.WithCode(c, "test"))
+		if got := status.Code(err); got != want {
+			t.Fatalf("code %d should be %s, got %s", c, want, got)
+		}
+		if got, ok := ErrorCode(err); !ok || got != c {
+			t.Fatalf("code %d should be kept in details, got %d", c, got)
+		}
+	}
+	if toStatus(nil) != nil {
+		t.Fatal("nil error should be nil status")
+	}
+}
+
+func TestCreateUserValidation(t *testing.T) {
+	cache := &Cache{}
+	_, err := cache.CreateUser(context.TODO(), &pb.CreateUserRequest{User: &pb.UserInfo{Name: "test"}})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatal("missing fields should be InvalidArgument, got:", err)
+	}
+}
+
+func TestUpdateUserMask(t *testing.T) {
+	cache := &Cache{}
+	_, err := cache.UpdateUser(context.TODO(), &pb.UpdateUserRequest{
+		User:       &pb.UserInfo{Name: "test"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"password"}},
+	})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatal("password can not be updated by mask, got:", err)
+	}
+}
+
+func TestChangePasswordValidation(t *testing.T) {
+	cache := &Cache{}
+	_, err := cache.ChangePassword(context.TODO(), &pb.ChangePasswordRequest{Name: "test", OldPassword: "old"})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatal("empty new password should be InvalidArgument, got:", err)
+	}
+}
+
+// newCaches 返回使用不同 store 实现的 Cache，同一组用例在所有实现上运行
+func newCaches(t *testing.T) map[string]*Cache {
+	sqlite, err := store.NewSQLiteFactory("file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = sqlite.Close() })
+	return map[string]*Cache{
+		"fake":   NewCache(fake.NewFactory(), 0),
+		"sqlite": NewCache(sqlite, 0),
+	}
+}
+
+func createUsers(t *testing.T, cache *Cache, names ...string) {
+	for _, name := range names {
+		_, err := cache.CreateUser(context.TODO(), &pb.CreateUserRequest{
+			User:     &pb.UserInfo{Name: name, Nickname: name, Email: name + "@example.com"},
+			Password: name + "-password",
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestUserService(t *testing.T) {
+	ctx := context.TODO()
+	limit := int64(1)
+	tests := []struct {
+		name string
+		call func(cache *Cache) error
+		want codes.Code
+	}{
+		{"create duplicate", func(cache *Cache) error {
+			_, err := cache.CreateUser(ctx, &pb.CreateUserRequest{
+				User:     &pb.UserInfo{Name: "tom", Nickname: "tom", Email: "tom@example.com"},
+				Password: "password",
+			})
+			return err
+		}, codes.AlreadyExists},
+		{"get", func(cache *Cache) error {
+			user, err := cache.GetUser(ctx, &pb.GetUserRequest{Name: "tom"})
+			if err == nil && (user.Email != "tom@example.com" || !user.CreatedAt.IsValid()) {
+				return fmt.Errorf("unexpected user: %v", user)
+			}
+			return err
+		}, codes.OK},
+		{"get not found", func(cache *Cache) error {
+			_, err := cache.GetUser(ctx, &pb.GetUserRequest{Name: "nobody"})
+			return err
+		}, codes.NotFound},
+		{"update with mask", func(cache *Cache) error {
+			user, err := cache.UpdateUser(ctx, &pb.UpdateUserRequest{
+				User:       &pb.UserInfo{Name: "tom", Nickname: "tommy", Email: "ignored@example.com"},
+				UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"nickname"}},
+			})
+			if err != nil {
+				return err
+			}
+			if user, err = cache.GetUser(ctx, &pb.GetUserRequest{Name: "tom"}); err != nil {
+				return err
+			}
+			if user.Nickname != "tommy" || user.Email != "tom@example.com" {
+				return fmt.Errorf("only nickname should be updated, got: %v", user)
+			}
+			return nil
+		}, codes.OK},
+		{"update with stale updated_at", func(cache *Cache) error {
+			user, err := cache.GetUser(ctx, &pb.GetUserRequest{Name: "tom"})
+			if err != nil {
+				return err
+			}
+			// 两个客户端基于同一个版本更新，第二个更新被拒绝，不会覆盖第一个客户端的修改
+			for _, nickname := range []string{"tom1", "tom2"} {
+				_, err = cache.UpdateUser(ctx, &pb.UpdateUserRequest{
+					User:       &pb.UserInfo{Name: "tom", Nickname: nickname, UpdatedAt: user.UpdatedAt},
+					UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"nickname"}},
+				})
+				if nickname == "tom1" && err != nil {
+					return err
+				}
+			}
+			return err
+		}, codes.Aborted},
+		{"update not found", func(cache *Cache) error {
+			_, err := cache.UpdateUser(ctx, &pb.UpdateUserRequest{User: &pb.UserInfo{Name: "nobody"}})
+			return err
+		}, codes.NotFound},
+		{"delete", func(cache *Cache) error {
+			if _, err := cache.DeleteUser(ctx, &pb.DeleteUserRequest{Name: "jerry"}); err != nil {
+				return err
+			}
+			_, err := cache.GetUser(ctx, &pb.GetUserRequest{Name: "jerry"})
+			return err
+		}, codes.NotFound},
+		{"delete soft deleted", func(cache *Cache) error {
+			_, err := cache.DeleteUser(ctx, &pb.DeleteUserRequest{Name: "jerry"})
+			return err
+		}, codes.NotFound},
+		{"delete without name", func(cache *Cache) error {
+			_, err := cache.DeleteUser(ctx, &pb.DeleteUserRequest{})
+			return err
+		}, codes.InvalidArgument},
+		{"list with zero limit", func(cache *Cache) error {
+			zero := int64(0)
+			_, err := cache.ListUsers(ctx, &pb.ListUsersRequest{Limit: &zero})
+			return err
+		}, codes.InvalidArgument},
+		{"list with limit over max", func(cache *Cache) error {
+			large := int64(maxPageSize * 10)
+			reply, err := cache.ListUsers(ctx, &pb.ListUsersRequest{Limit: &large})
+			if err == nil && len(reply.Items) != 2 {
+				return fmt.Errorf("unexpected reply: %v", reply)
+			}
+			return err
+		}, codes.OK},
+		{"list pages", func(cache *Cache) error {
+			first, err := cache.ListUsers(ctx, &pb.ListUsersRequest{Limit: &limit})
+			if err != nil {
+				return err
+			}
+			if first.Count != 2 || len(first.Items) != 1 || first.Items[0].Name != "spike" || first.NextPageToken == "" {
+				return fmt.Errorf("unexpected first page: %v", first)
+			}
+			second, err := cache.ListUsers(ctx, &pb.ListUsersRequest{Limit: &limit, PageToken: first.NextPageToken})
+			if err != nil {
+				return err
+			}
+			if len(second.Items) != 1 || second.NextPageToken != "" || second.Items[0].Name != "tom" {
+				return fmt.Errorf("unexpected second page: %v", second)
+			}
+			return nil
+		}, codes.OK},
+		{"list with field selector", func(cache *Cache) error {
+			reply, err := cache.ListUsers(ctx, &pb.ListUsersRequest{FieldSelector: "name!=tom"})
+			if err == nil && reply.Count != 1 {
+				return fmt.Errorf("unexpected reply: %v", reply)
+			}
+			return err
+		}, codes.OK},
+		{"list with invalid field selector", func(cache *Cache) error {
+			_, err := cache.ListUsers(ctx, &pb.ListUsersRequest{FieldSelector: "password=123"})
+			return err
+		}, codes.InvalidArgument},
+		{"verify password", func(cache *Cache) error {
+			_, err := cache.VerifyPassword(ctx, &pb.VerifyPasswordRequest{Name: "tom", Password: "tom-password"})
+			return err
+		}, codes.OK},
+		{"verify wrong password", func(cache *Cache) error {
+			_, err := cache.VerifyPassword(ctx, &pb.VerifyPasswordRequest{Name: "tom", Password: "wrong"})
+			return err
+		}, codes.Unauthenticated},
+		{"verify password of missing user", func(cache *Cache) error {
+			_, err := cache.VerifyPassword(ctx, &pb.VerifyPasswordRequest{Name: "nobody", Password: "wrong"})
+			return err
+		}, codes.Unauthenticated},
+		{"change password", func(cache *Cache) error {
+			_, err := cache.ChangePassword(ctx, &pb.ChangePasswordRequest{
+				Name: "tom", OldPassword: "tom-password", NewPassword: "new-password",
+			})
+			if err != nil {
+				return err
+			}
+			_, err = cache.VerifyPassword(ctx, &pb.VerifyPasswordRequest{Name: "tom", Password: "new-password"})
+			return err
+		}, codes.OK},
+	}
+
+	for name, cache := range newCaches(t) {
+		t.Run(name, func(t *testing.T) {
+			// 用例依次执行：jerry 在 delete 用例中被软删除，之后 list 只能看到 tom 和 spike
+			createUsers(t, cache, "tom", "jerry", "spike", "tyke")
+			if _, err := cache.DeleteUser(ctx, &pb.DeleteUserRequest{Name: "tyke", Unscoped: true}); err != nil {
+				t.Fatal(err)
+			}
+			for _, tt := range tests {
+				if got := status.Code(tt.call(cache)); got != tt.want {
+					t.Fatalf("%s: want %s, got %s", tt.name, tt.want, got)
+				}
+			}
+		})
+	}
+}
+
+// countingFactory 统计 store 中 Get 的调用次数
+type countingFactory struct {
+	store.Factory
+	gets *int
+}
+
+type countingUsers struct {
+	store.UserStore
+	gets *int
+}
+
+func (f countingFactory) Users() store.UserStore {
+	return countingUsers{UserStore: f.Factory.Users(), gets: f.gets}
+}
+
+func (u countingUsers) Get(ctx context.Context, username string, opts metav1.GetOptions) (*v1.User, error) {
+	*u.gets++
+	return u.UserStore.Get(ctx, username, opts)
+}
+
+func TestCacheReadThrough(t *testing.T) {
+	ctx := context.TODO()
+	gets := 0
+	cache := NewCache(countingFactory{Factory: fake.NewFactory(), gets: &gets}, 0)
+	createUsers(t, cache, "tom", "jerry")
+
+	for i := 0; i < 3; i++ {
+		if _, err := cache.GetUser(ctx, &pb.GetUserRequest{Name: "tom"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if gets != 1 {
+		t.Fatal("tom should be loaded from store once, got:", gets)
+	}
+
+	// 更新时 UpdateUser 读取一次 store，之后缓存失效，GetUser 重新加载
+	_, err := cache.UpdateUser(ctx, &pb.UpdateUserRequest{
+		User:       &pb.UserInfo{Name: "tom", Nickname: "tommy"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"nickname"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if user, err := cache.GetUser(ctx, &pb.GetUserRequest{Name: "tom"}); err != nil || user.Nickname != "tommy" || gets != 3 {
+		t.Fatalf("updated user should be reloaded, got: %v, %v, gets: %d", user, err, gets)
+	}
+
+	// ListUsers 的结果不写入缓存，否则可能覆盖并发的 UpdateUser 或 DeleteUser 删除缓存后的结果
+	if _, err := cache.ListUsers(ctx, &pb.ListUsersRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.GetUser(ctx, &pb.GetUserRequest{Name: "jerry"}); err != nil || gets != 4 {
+		t.Fatalf("jerry should be loaded from store, got: %v, gets: %d", err, gets)
+	}
+
+	if _, err := cache.DeleteUser(ctx, &pb.DeleteUserRequest{Name: "tom"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cache.GetUser(ctx, &pb.GetUserRequest{Name: "tom"}); status.Code(err) != codes.NotFound {
+		t.Fatal("deleted user should not be served from cache, got:", err)
+	}
+
+	stats := cache.Stats()
+	if stats.Gets != 6 || stats.Hits != 2 || stats.Misses != 4 || stats.LoadErrors != 1 {
+		t.Fatalf("unexpected stats: %+v", stats)
+	}
+
+	w := httptest.NewRecorder()
+	cache.StatsHandler().ServeHTTP(w, httptest.NewRequest("GET", "/debug/cache", nil))
+	var served geecache.Stats
+	if err := json.Unmarshal(w.Body.Bytes(), &served); err != nil || served != stats {
+		t.Fatalf("StatsHandler should serve %+v, got %s", stats, w.Body)
+	}
+}
+
+func TestDummyPassword(t *testing.T) {
+	// dummyPassword 必须是合法的 bcrypt 哈希，并且 cost 和 store 加密密码时一致，否则响应时间仍然不同
+	if err := auth.Compare(dummyPassword, "dummy-password-for-timing"); err != nil {
+		t.Fatal("dummyPassword should be a valid bcrypt hash, got:", err)
+	}
+	hashed, _ := auth.Encrypt("password")
+	if hashed[:7] != dummyPassword[:7] {
+		t.Fatalf("dummyPassword should use the same cost as auth.Encrypt, want %s, got %s", hashed[:7], dummyPassword[:7])
+	}
+}
+
+// limitFactory 记录 store 的 List 收到的 limit
+type limitFactory struct {
+	store.Factory
+	limit *int64
+}
+
+type limitUsers struct {
+	store.UserStore
+	limit *int64
+}
+
+func (f limitFactory) Users() store.UserStore {
+	return limitUsers{UserStore: f.Factory.Users(), limit: f.limit}
+}
+
+func (u limitUsers) List(ctx context.Context, opts metav1.ListOptions) (*v1.UserList, error) {
+	*u.limit = *opts.Limit
+	return u.UserStore.List(ctx, opts)
+}
+
+func TestListUsersMaxPageSize(t *testing.T) {
+	var limit int64
+	cache := NewCache(limitFactory{Factory: fake.NewFactory(), limit: &limit}, 0)
+	large := int64(maxPageSize + 1)
+	if _, err := cache.ListUsers(context.TODO(), &pb.ListUsersRequest{Limit: &large}); err != nil {
+		t.Fatal(err)
+	}
+	if limit != maxPageSize {
+		t.Fatalf("limit should be capped at %d, got %d", maxPageSize, limit)
+	}
+}
@@ -0,0 +1,79 @@
+package server
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/go-examples-with-tests/database/v4/store"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// UserServiceName 是 UserService 在健康检查中的服务名
+const UserServiceName = "pb.UserService"
+
+// HealthChecker 定期检查数据库是否可以访问，并据此更新 grpc.health.v1 中的服务状态
+type HealthChecker struct {
+	*health.Server
+	store    store.Factory
+	interval time.Duration
+}
+
+func NewHealthChecker(sf store.Factory, interval time.Duration) *HealthChecker {
+	return &HealthChecker{Server: health.NewServer(), store: sf, interval: interval}
+}
+
+// Register 在 s 上注册健康检查服务
+func (h *HealthChecker) Register(s *grpc.Server) {
+	healthpb.RegisterHealthServer(s, h.Server)
+}
+
+// Run 立即检查一次，之后每隔 interval 检查一次，直到 ctx 结束
+func (h *HealthChecker) Run(ctx context.Context) {
+	ticker := time.NewTicker(h.interval)
+	defer ticker.Stop()
+	for {
+		h.check(ctx)
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (h *HealthChecker) check(ctx context.Context) {
+	ctx, cancel := context.WithTimeout(ctx, h.interval)
+	defer cancel()
+
+	status := healthpb.HealthCheckResponse_SERVING
+	if err := h.store.Ping(ctx); err != nil {
+		log.Printf("health check failed: %s", err.Error())
+		status = healthpb.HealthCheckResponse_NOT_SERVING
+	}
+	// 空的服务名表示整个服务器的状态
+	h.SetServingStatus("", status)
+	h.SetServingStatus(UserServiceName, status)
+}
+
+// GracefulStop 等待正在处理的请求结束后关闭 s，超过 timeout 时强制关闭，返回是否在 timeout 内正常关闭
+func GracefulStop(s *grpc.Server, timeout time.Duration) bool {
+	done := make(chan struct{})
+	go func() {
+		s.GracefulStop()
+		close(done)
+	}()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case <-done:
+		return true
+	case <-timer.C:
+		s.Stop()
+		<-done
+		return false
+	}
+}
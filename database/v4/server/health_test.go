@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-examples-with-tests/database/v4/store"
+	"github.com/go-examples-with-tests/database/v4/store/fake"
+	"google.golang.org/grpc"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// unreachableFactory 模拟无法访问的数据库
+type unreachableFactory struct {
+	store.Factory
+}
+
+func (unreachableFactory) Ping(ctx context.Context) error {
+	return errors.New("connection refused")
+}
+
+func TestHealthChecker(t *testing.T) {
+	tests := []struct {
+		name    string
+		factory store.Factory
+		want    healthpb.HealthCheckResponse_ServingStatus
+	}{
+		{"reachable", fake.NewFactory(), healthpb.HealthCheckResponse_SERVING},
+		{"unreachable", unreachableFactory{fake.NewFactory()}, healthpb.HealthCheckResponse_NOT_SERVING},
+	}
+	for _, tt := range tests {
+		checker := NewHealthChecker(tt.factory, time.Minute)
+		checker.check(context.TODO())
+		for _, service := range []string{"", UserServiceName} {
+			resp, err := checker.Check(context.TODO(), &healthpb.HealthCheckRequest{Service: service})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if resp.Status != tt.want {
+				t.Fatalf("%s: service %q want %s, got %s", tt.name, service, tt.want, resp.Status)
+			}
+		}
+
+		checker.Shutdown()
+		resp, _ := checker.Check(context.TODO(), &healthpb.HealthCheckRequest{Service: UserServiceName})
+		if resp.Status != healthpb.HealthCheckResponse_NOT_SERVING {
+			t.Fatalf("%s: should be NOT_SERVING after shutdown, got %s", tt.name, resp.Status)
+		}
+	}
+}
+
+func TestHealthCheckerRun(t *testing.T) {
+	checker := NewHealthChecker(fake.NewFactory(), time.Millisecond)
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	checker.Run(ctx)
+
+	resp, err := checker.Check(context.TODO(), &healthpb.HealthCheckRequest{})
+	if err != nil || resp.Status != healthpb.HealthCheckResponse_SERVING {
+		t.Fatal("want SERVING, got:", resp, err)
+	}
+}
+
+func TestGracefulStop(t *testing.T) {
+	opts := NewServerOption()
+	s := grpc.NewServer(opts.GRPCOptions()...)
+	if !GracefulStop(s, time.Second) {
+		t.Fatal("idle server should stop gracefully")
+	}
+}
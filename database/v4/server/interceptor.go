@@ -0,0 +1,262 @@
+package server
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"golang.org/x/time/rate"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// RequestIDKey 是请求 ID 在 metadata 中的 key
+const RequestIDKey = "x-request-id"
+
+type contextKey int
+
+const (
+	requestIDContextKey contextKey = iota
+	subjectContextKey
+)
+
+// publicMethods 中的服务不需要认证，例如 gRPC 反射和健康检查
+var publicMethods = []string{
+	"/grpc.reflection.",
+	"/grpc.health.",
+}
+
+// GRPCOptions 返回创建 grpc.Server 所需的选项，拦截器依次为：
+// 请求 ID、访问日志、panic 恢复、认证和限流，前面的拦截器可以观察到后面拦截器的结果
+func (opts *ServerOption) GRPCOptions() []grpc.ServerOption {
+	unary := []grpc.UnaryServerInterceptor{requestIDUnary, loggingUnary, recoveryUnary}
+	stream := []grpc.StreamServerInterceptor{requestIDStream, loggingStream, recoveryStream}
+	if opts.JWTKey != "" {
+		auth := jwtAuthFunc([]byte(opts.JWTKey))
+		unary = append(unary, authUnary(auth))
+		stream = append(stream, authStream(auth))
+	}
+	if opts.RateLimit > 0 {
+		limiter := newMethodLimiter(rate.Limit(opts.RateLimit), opts.RateBurst)
+		unary = append(unary, limiter.unary)
+		stream = append(stream, limiter.stream)
+	}
+
+	return []grpc.ServerOption{
+		grpc.MaxRecvMsgSize(opts.MaxRecvMsgSize),
+		grpc.ChainUnaryInterceptor(unary...),
+		grpc.ChainStreamInterceptor(stream...),
+	}
+}
+
+// RequestID 返回当前请求的 ID
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDContextKey).(string)
+	return id
+}
+
+// Subject 返回认证通过后 token 中的 sub，未开启认证时为空
+func Subject(ctx context.Context) string {
+	sub, _ := ctx.Value(subjectContextKey).(string)
+	return sub
+}
+
+// serverStream 用于在流式 RPC 中替换 context
+type serverStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *serverStream) Context() context.Context {
+	return s.ctx
+}
+
+// withRequestID 从 metadata 中读取请求 ID，没有时生成一个新的，
+// 同时写入 outgoing metadata，在当前服务调用其他服务时继续传递
+func withRequestID(ctx context.Context) (context.Context, string) {
+	var id string
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		if ids := md.Get(RequestIDKey); len(ids) > 0 {
+			id = ids[0]
+		}
+	}
+	if id == "" {
+		b := make([]byte, 16)
+		_, _ = rand.Read(b)
+		id = hex.EncodeToString(b)
+	}
+	ctx = context.WithValue(ctx, requestIDContextKey, id)
+	return metadata.AppendToOutgoingContext(ctx, RequestIDKey, id), id
+}
+
+func requestIDUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	ctx, id := withRequestID(ctx)
+	_ = grpc.SetHeader(ctx, metadata.Pairs(RequestIDKey, id))
+	return handler(ctx, req)
+}
+
+func requestIDStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, id := withRequestID(ss.Context())
+	_ = ss.SetHeader(metadata.Pairs(RequestIDKey, id))
+	return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+}
+
+func accessLog(ctx context.Context, method string, start time.Time, err error) {
+	addr := ""
+	if p, ok := peer.FromContext(ctx); ok {
+		addr = p.Addr.String()
+	}
+	log.Printf("grpc access: method=%s request_id=%s peer=%s code=%s latency=%s",
+		method, RequestID(ctx), addr, status.Code(err), time.Since(start))
+}
+
+func loggingUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	start := time.Now()
+	resp, err := handler(ctx, req)
+	accessLog(ctx, info.FullMethod, start, err)
+	return resp, err
+}
+
+func loggingStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	start := time.Now()
+	err := handler(srv, ss)
+	accessLog(ss.Context(), info.FullMethod, start, err)
+	return err
+}
+
+// recovered 将 panic 转换为 codes.Internal，堆栈只打印到日志中，不返回给调用方
+func recovered(ctx context.Context, method string, err *error) {
+	if r := recover(); r != nil {
+		log.Printf("grpc panic: method=%s request_id=%s panic=%v\n%s", method, RequestID(ctx), r, debug.Stack())
+		*err = status.Error(codes.Internal, "internal server error")
+	}
+}
+
+func recoveryUnary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
+	defer recovered(ctx, info.FullMethod, &err)
+	return handler(ctx, req)
+}
+
+func recoveryStream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+	defer recovered(ss.Context(), info.FullMethod, &err)
+	return handler(srv, ss)
+}
+
+// authFunc 校验 token，返回 token 对应的用户
+type authFunc func(token string) (string, error)
+
+// jwtAuthFunc 使用 HS256 和 key 校验 JWT，返回其中的 sub
+func jwtAuthFunc(key []byte) authFunc {
+	return func(token string) (string, error) {
+		claims := &jwt.RegisteredClaims{}
+		_, err := jwt.ParseWithClaims(token, claims, func(t *jwt.Token) (interface{}, error) {
+			if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+				return nil, fmt.Errorf("unexpected signing method: %v", t.Header["alg"])
+			}
+			return key, nil
+		})
+		if err != nil {
+			return "", err
+		}
+		return claims.Subject, nil
+	}
+}
+
+// authenticate 从 metadata 的 authorization 中读取 Bearer token 并校验
+func authenticate(ctx context.Context, method string, auth authFunc) (context.Context, error) {
+	for _, prefix := range publicMethods {
+		if strings.HasPrefix(method, prefix) {
+			return ctx, nil
+		}
+	}
+
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "missing authorization token")
+	}
+	const prefix = "Bearer "
+	if len(values[0]) <= len(prefix) || !strings.EqualFold(values[0][:len(prefix)], prefix) {
+		return nil, status.Error(codes.Unauthenticated, "authorization must be a bearer token")
+	}
+	sub, err := auth(values[0][len(prefix):])
+	if err != nil {
+		return nil, status.Errorf(codes.Unauthenticated, "invalid token: %v", err)
+	}
+	return context.WithValue(ctx, subjectContextKey, sub), nil
+}
+
+func authUnary(auth authFunc) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := authenticate(ctx, info.FullMethod, auth)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+func authStream(auth authFunc) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := authenticate(ss.Context(), info.FullMethod, auth)
+		if err != nil {
+			return err
+		}
+		return handler(srv, &serverStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// methodLimiter 为每个方法维护一个令牌桶
+type methodLimiter struct {
+	limit rate.Limit
+	burst int
+
+	mu       sync.Mutex
+	limiters map[string]*rate.Limiter
+}
+
+func newMethodLimiter(limit rate.Limit, burst int) *methodLimiter {
+	if burst < 1 {
+		burst = 1
+	}
+	return &methodLimiter{limit: limit, burst: burst, limiters: make(map[string]*rate.Limiter)}
+}
+
+func (l *methodLimiter) allow(method string) error {
+	l.mu.Lock()
+	limiter, ok := l.limiters[method]
+	if !ok {
+		limiter = rate.NewLimiter(l.limit, l.burst)
+		l.limiters[method] = limiter
+	}
+	l.mu.Unlock()
+
+	if !limiter.Allow() {
+		return status.Errorf(codes.ResourceExhausted, "%s is rate limited", method)
+	}
+	return nil
+}
+
+func (l *methodLimiter) unary(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	if err := l.allow(info.FullMethod); err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (l *methodLimiter) stream(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	if err := l.allow(info.FullMethod); err != nil {
+		return err
+	}
+	return handler(srv, ss)
+}
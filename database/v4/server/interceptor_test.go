@@ -0,0 +1,136 @@
+package server
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/go-examples-with-tests/database/v4/pb"
+	"github.com/go-examples-with-tests/database/v4/store"
+	"github.com/go-examples-with-tests/database/v4/store/fake"
+	"github.com/golang-jwt/jwt/v4"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// panicFactory 用于测试 panic 恢复
+type panicFactory struct{}
+
+func (panicFactory) Users() store.UserStore         { panic("users store is broken") }
+func (panicFactory) Ping(ctx context.Context) error { return nil }
+func (panicFactory) Close() error                   { return nil }
+
+// dial 使用 bufconn 在内存中启动 gRPC 服务，返回对应的客户端
+func dial(t *testing.T, opts *ServerOption, factory store.Factory) pb.UserServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := grpc.NewServer(opts.GRPCOptions()...)
+	pb.RegisterUserServiceServer(server, NewCache(factory, 0))
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.DialContext(context.TODO(), "bufnet", grpc.WithInsecure(),
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return listener.Dial()
+		}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { _ = conn.Close() })
+	return pb.NewUserServiceClient(conn)
+}
+
+func TestRequestID(t *testing.T) {
+	client := dial(t, NewServerOption(), fake.NewFactory())
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.TODO(), RequestIDKey, "req-1")
+	_, err := client.GetUser(ctx, &pb.GetUserRequest{Name: "nobody"}, grpc.Header(&header))
+	if status.Code(err) != codes.NotFound {
+		t.Fatal("want NotFound, got:", err)
+	}
+	if ids := header.Get(RequestIDKey); len(ids) != 1 || ids[0] != "req-1" {
+		t.Fatal("request id should be propagated, got:", ids)
+	}
+
+	_, _ = client.GetUser(context.TODO(), &pb.GetUserRequest{Name: "nobody"}, grpc.Header(&header))
+	if ids := header.Get(RequestIDKey); len(ids) != 1 || len(ids[0]) != 32 {
+		t.Fatal("request id should be generated, got:", ids)
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	client := dial(t, NewServerOption(), panicFactory{})
+	_, err := client.GetUser(context.TODO(), &pb.GetUserRequest{Name: "tom"})
+	if status.Code(err) != codes.Internal {
+		t.Fatal("panic should be recovered as Internal, got:", err)
+	}
+}
+
+func TestAuth(t *testing.T) {
+	opts := NewServerOption()
+	opts.JWTKey = "secret"
+	client := dial(t, opts, fake.NewFactory())
+
+	sign := func(key string, expiresAt time.Time) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.RegisteredClaims{Subject: "admin", ExpiresAt: jwt.NewNumericDate(expiresAt)})
+		signed, err := token.SignedString([]byte(key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return signed
+	}
+	tests := []struct {
+		name          string
+		authorization string
+		want          codes.Code
+	}{
+		{"missing token", "", codes.Unauthenticated},
+		{"not bearer", "Basic YWRtaW46YWRtaW4=", codes.Unauthenticated},
+		{"wrong key", "Bearer " + sign("other", time.Now().Add(time.Minute)), codes.Unauthenticated},
+		{"expired", "Bearer " + sign("secret", time.Now().Add(-time.Minute)), codes.Unauthenticated},
+		{"valid", "Bearer " + sign("secret", time.Now().Add(time.Minute)), codes.NotFound},
+	}
+	for _, tt := range tests {
+		ctx := context.TODO()
+		if tt.authorization != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", tt.authorization)
+		}
+		_, err := client.GetUser(ctx, &pb.GetUserRequest{Name: "nobody"})
+		if got := status.Code(err); got != tt.want {
+			t.Fatalf("%s: want %s, got %s", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	opts := NewServerOption()
+	opts.RateLimit = 0.001
+	opts.RateBurst = 1
+	client := dial(t, opts, fake.NewFactory())
+
+	if _, err := client.GetUser(context.TODO(), &pb.GetUserRequest{Name: "nobody"}); status.Code(err) != codes.NotFound {
+		t.Fatal("first request should pass, got:", err)
+	}
+	if _, err := client.GetUser(context.TODO(), &pb.GetUserRequest{Name: "nobody"}); status.Code(err) != codes.ResourceExhausted {
+		t.Fatal("second request should be limited, got:", err)
+	}
+	// 限流是按方法计算的
+	if _, err := client.ListUsers(context.TODO(), &pb.ListUsersRequest{}); err != nil {
+		t.Fatal("other methods should not be limited, got:", err)
+	}
+}
+
+func TestMaxRecvMsgSize(t *testing.T) {
+	client := dial(t, NewServerOption(), fake.NewFactory())
+	_, err := client.CreateUser(context.TODO(), &pb.CreateUserRequest{
+		User:     &pb.UserInfo{Name: "tom", Nickname: "tom", Email: "tom@example.com", Phone: "123456789"},
+		Password: "a password longer than one hundred bytes, which was rejected by the old MaxRecvMsgSize(100) option",
+	})
+	if err != nil {
+		t.Fatal("requests larger than 100 bytes should be accepted, got:", err)
+	}
+}
@@ -1,13 +1,116 @@
 package server
 
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"time"
+
+	"github.com/spf13/pflag"
+)
+
 type ServerOption struct {
-	Addr string
-	Port string
+	Addr           string  `json:"addr"              mapstructure:"addr"`
+	Port           string  `json:"port"              mapstructure:"port"`
+	HTTPPort       string  `json:"http-port"         mapstructure:"http-port"`
+	AdminAddr      string  `json:"admin-addr"        mapstructure:"admin-addr"`
+	MaxRecvMsgSize int     `json:"max-recv-msg-size" mapstructure:"max-recv-msg-size"`
+	JWTKey         string  `json:"-"                 mapstructure:"jwt-key"`
+	RateLimit      float64 `json:"rate-limit"        mapstructure:"rate-limit"`
+	RateBurst      int     `json:"rate-burst"        mapstructure:"rate-burst"`
+
+	CacheBytes          int64         `json:"cache-bytes"           mapstructure:"cache-bytes"`
+	WatchHistory        int           `json:"watch-history"         mapstructure:"watch-history"`
+	WatchBuffer         int           `json:"watch-buffer"          mapstructure:"watch-buffer"`
+	HealthCheckInterval time.Duration `json:"health-check-interval" mapstructure:"health-check-interval"`
+	ShutdownTimeout     time.Duration `json:"shutdown-timeout"      mapstructure:"shutdown-timeout"`
 }
 
 func NewServerOption() *ServerOption {
 	return &ServerOption{
-		Addr: "127.0.0.1",
-		Port: ":8081",
+		Addr:           "127.0.0.1",
+		Port:           ":8081",
+		HTTPPort:       ":8080",          // REST 网关的端口，为空时不启动
+		AdminAddr:      "127.0.0.1:9090", // 缓存统计等管理接口的地址，只能监听本机，为空时不启动
+		MaxRecvMsgSize: 4 * 1024 * 1024,  // 与 gRPC 的默认值相同
+		JWTKey:         "",               // 为空时不开启认证
+		RateLimit:      0,                // 为 0 时不限流
+		RateBurst:      10,
+
+		CacheBytes:          64 * 1024 * 1024,
+		WatchHistory:        1000,
+		WatchBuffer:         100,
+		HealthCheckInterval: 10 * time.Second,
+		ShutdownTimeout:     10 * time.Second,
+	}
+}
+
+// AddFlags 将 gRPC 服务相关的命令行参数添加到 fs 中
+func (opts *ServerOption) AddFlags(fs *pflag.FlagSet) {
+	fs.StringVar(&opts.Addr, "server.addr", opts.Addr, "The IP address on which to serve the gRPC service.")
+	fs.StringVar(&opts.Port, "server.port", opts.Port, "The port on which to serve the gRPC service, e.g. :8081.")
+	fs.StringVar(&opts.HTTPPort, "server.http-port", opts.HTTPPort,
+		"The port on which to serve the REST gateway, e.g. :8080. The gateway is disabled if empty.")
+	fs.StringVar(&opts.AdminAddr, "server.admin-addr", opts.AdminAddr,
+		"The loopback address on which to serve the admin endpoints, e.g. 127.0.0.1:9090. Disabled if empty.")
+	fs.IntVar(&opts.MaxRecvMsgSize, "server.max-recv-msg-size", opts.MaxRecvMsgSize,
+		"The max message size in bytes the server can receive.")
+	fs.StringVar(&opts.JWTKey, "server.jwt-key", opts.JWTKey,
+		"The HS256 key used to verify bearer tokens, authentication is disabled if empty.")
+	fs.Float64Var(&opts.RateLimit, "server.rate-limit", opts.RateLimit,
+		"Requests per second allowed for each method, 0 means unlimited.")
+	fs.IntVar(&opts.RateBurst, "server.rate-burst", opts.RateBurst, "Maximum burst requests for each method.")
+	fs.Int64Var(&opts.CacheBytes, "server.cache-bytes", opts.CacheBytes,
+		"The max bytes of cached users, 0 means unlimited.")
+	fs.IntVar(&opts.WatchHistory, "server.watch-history", opts.WatchHistory,
+		"The number of recent user events kept for resuming WatchUsers.")
+	fs.IntVar(&opts.WatchBuffer, "server.watch-buffer", opts.WatchBuffer,
+		"The number of events buffered for each watcher, slow watchers are closed when the buffer is full.")
+	fs.DurationVar(&opts.HealthCheckInterval, "server.health-check-interval", opts.HealthCheckInterval,
+		"The interval of checking whether the database is reachable.")
+	fs.DurationVar(&opts.ShutdownTimeout, "server.shutdown-timeout", opts.ShutdownTimeout,
+		"The time to wait for in-flight requests before the server is forcibly stopped.")
+}
+
+// Validate 检查 Addr 和 Port 能否组成合法的监听地址，以及拦截器的配置
+func (opts *ServerOption) Validate() []error {
+	var errs []error
+	if _, port, err := net.SplitHostPort(opts.Addr + opts.Port); err != nil {
+		errs = append(errs, fmt.Errorf("invalid server address %q: %v", opts.Addr+opts.Port, err))
+	} else if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		errs = append(errs, fmt.Errorf("server.port %q must be in [1, 65535]", port))
+	}
+	if opts.HTTPPort != "" {
+		if _, err := net.ResolveTCPAddr("tcp", opts.Addr+opts.HTTPPort); err != nil {
+			errs = append(errs, fmt.Errorf("invalid server.http-port %q: %v", opts.HTTPPort, err))
+		}
+	}
+	// 管理接口没有认证，只允许监听本机的地址
+	if opts.AdminAddr != "" {
+		if host, _, err := net.SplitHostPort(opts.AdminAddr); err != nil {
+			errs = append(errs, fmt.Errorf("invalid server.admin-addr %q: %v", opts.AdminAddr, err))
+		} else if ip := net.ParseIP(host); host != "localhost" && (ip == nil || !ip.IsLoopback()) {
+			errs = append(errs, fmt.Errorf("server.admin-addr %q must be a loopback address", opts.AdminAddr))
+		}
+	}
+	if opts.MaxRecvMsgSize < 1 {
+		errs = append(errs, fmt.Errorf("server.max-recv-msg-size %d must be positive", opts.MaxRecvMsgSize))
+	}
+	if opts.CacheBytes < 0 {
+		errs = append(errs, fmt.Errorf("server.cache-bytes %d must not be negative", opts.CacheBytes))
+	}
+	if opts.WatchHistory < 1 || opts.WatchBuffer < 1 {
+		errs = append(errs, fmt.Errorf("server.watch-history %d and server.watch-buffer %d must be positive",
+			opts.WatchHistory, opts.WatchBuffer))
+	}
+	if opts.HealthCheckInterval <= 0 {
+		errs = append(errs, fmt.Errorf("server.health-check-interval %s must be positive", opts.HealthCheckInterval))
+	}
+	if opts.ShutdownTimeout < 0 {
+		errs = append(errs, fmt.Errorf("server.shutdown-timeout %s must not be negative", opts.ShutdownTimeout))
+	}
+	if opts.RateLimit < 0 {
+		errs = append(errs, fmt.Errorf("server.rate-limit %v must not be negative", opts.RateLimit))
 	}
+	return errs
 }
@@ -0,0 +1,58 @@
+package server
+
+import (
+	"strconv"
+
+	"github.com/go-examples-with-tests/database/v4/pkg/code"
+	"github.com/marmotedu/errors"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// ErrorDomain 是 status details 中 ErrorInfo 的 domain，ErrorInfo.Reason 为 pkg/code 中定义的错误码
+const ErrorDomain = "apiserver"
+
+// grpcCodes 是 store 层错误码到 gRPC 状态码的映射
+var grpcCodes = map[int]codes.Code{
+	code.ErrValidation:        codes.InvalidArgument,
+	code.ErrDatabase:          codes.Internal,
+	code.ErrEncrypt:           codes.Internal,
+	code.ErrPasswordIncorrect: codes.Unauthenticated,
+	code.ErrUserNotFound:      codes.NotFound,
+	code.ErrUserAlreadyExist:  codes.AlreadyExists,
+	code.ErrUserConflict:      codes.Aborted,
+}
+
+// toStatus 将 store 返回的 error 转换为 gRPC 的 status error，对外只暴露错误码对应的错误信息，
+// 错误码本身放在 ErrorInfo 中，HTTP 网关依据它返回与错误码对应的 HTTP 状态码
+func toStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	coder := errors.ParseCoder(err)
+	c, ok := grpcCodes[coder.Code()]
+	if !ok {
+		return status.Error(codes.Unknown, err.Error())
+	}
+	st, detailErr := status.New(c, coder.String()).WithDetails(&errdetails.ErrorInfo{
+		Reason: strconv.Itoa(coder.Code()),
+		Domain: ErrorDomain,
+	})
+	if detailErr != nil {
+		return status.Error(c, coder.String())
+	}
+	return st.Err()
+}
+
+// ErrorCode 返回 toStatus 放在 status details 中的错误码，没有时返回 false
+func ErrorCode(err error) (int, bool) {
+	for _, detail := range status.Convert(err).Details() {
+		if info, ok := detail.(*errdetails.ErrorInfo); ok && info.Domain == ErrorDomain {
+			if c, err := strconv.Atoi(info.Reason); err == nil {
+				return c, true
+			}
+		}
+	}
+	return 0, false
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-examples-with-tests/database/v4/pb"
+	"github.com/go-examples-with-tests/database/v4/store"
+	"github.com/go-examples-with-tests/database/v4/store/fake"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/fieldmaskpb"
+)
+
+func TestWatchUsers(t *testing.T) {
+	events := store.NewBroadcaster(2, 10)
+	client := dial(t, NewServerOption(), store.WithEvents(fake.NewFactory(), events))
+	ctx := context.TODO()
+
+	// 从 revision 1 开始订阅，避免订阅之前发布的事件被错过
+	stream, err := client.WatchUsers(ctx, &pb.WatchUsersRequest{StartRevision: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.CreateUser(ctx, &pb.CreateUserRequest{
+		User:     &pb.UserInfo{Name: "tom", Nickname: "tom", Email: "tom@example.com"},
+		Password: "secret",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = client.UpdateUser(ctx, &pb.UpdateUserRequest{
+		User:       &pb.UserInfo{Name: "tom", Nickname: "tommy"},
+		UpdateMask: &fieldmaskpb.FieldMask{Paths: []string{"nickname"}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		typ      pb.UserEvent_Type
+		revision int64
+		nickname string
+	}{
+		{pb.UserEvent_CREATED, 1, "tom"},
+		{pb.UserEvent_UPDATED, 2, "tommy"},
+	}
+	for _, tt := range tests {
+		event, err := stream.Recv()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if event.Type != tt.typ || event.Revision != tt.revision || event.User.Nickname != tt.nickname {
+			t.Fatalf("want %s at %d, got %v", tt.typ, tt.revision, event)
+		}
+	}
+
+	// 历史记录只保留 2 个事件，revision 1 被压缩后无法恢复
+	if _, err := client.DeleteUser(ctx, &pb.DeleteUserRequest{Name: "tom"}); err != nil {
+		t.Fatal(err)
+	}
+	if event, err := stream.Recv(); err != nil || event.Type != pb.UserEvent_DELETED {
+		t.Fatal("want DELETED event, got:", event, err)
+	}
+	compacted, _ := client.WatchUsers(ctx, &pb.WatchUsersRequest{StartRevision: 1})
+	if _, err := compacted.Recv(); status.Code(err) != codes.OutOfRange {
+		t.Fatal("compacted revision should be OutOfRange, got:", err)
+	}
+
+	// 服务退出时关闭所有的订阅
+	events.Close()
+	if _, err := stream.Recv(); status.Code(err) != codes.Unavailable {
+		t.Fatal("stream should be closed as Unavailable, got:", err)
+	}
+}
+
+func TestWatchUsersUnimplemented(t *testing.T) {
+	client := dial(t, NewServerOption(), fake.NewFactory())
+	stream, err := client.WatchUsers(context.TODO(), &pb.WatchUsersRequest{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := stream.Recv(); status.Code(err) != codes.Unimplemented {
+		t.Fatal("store without events should be Unimplemented, got:", err)
+	}
+}
@@ -0,0 +1,268 @@
+package store
+
+import (
+	"context"
+	"sync"
+
+	"github.com/go-examples-with-tests/database/v4/pkg/code"
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+	"github.com/marmotedu/errors"
+)
+
+type EventType int
+
+const (
+	EventCreated EventType = iota + 1
+	EventUpdated
+	EventDeleted
+)
+
+var (
+	// ErrCompacted 表示要恢复的 revision 不在历史记录中：已经被新的事件覆盖，
+	// 或者大于下一个 revision，例如服务重启后 revision 从 1 重新开始
+	ErrCompacted = errors.New("required revision has been compacted")
+	// ErrSlowConsumer 表示订阅者的缓冲区已满，订阅被关闭，订阅者可以从最后收到的 revision 之后重新订阅
+	ErrSlowConsumer = errors.New("subscriber is too slow")
+	// ErrBroadcasterClosed 表示 Broadcaster 已经关闭，例如服务正在退出
+	ErrBroadcasterClosed = errors.New("broadcaster is closed")
+)
+
+// Event 是用户的变更事件，Revision 从 1 开始单调递增，删除事件中的 User 只有用户名
+type Event struct {
+	Revision int64
+	Type     EventType
+	User     *v1.User
+}
+
+// EventSource 由能够产生变更事件的 Factory 实现
+type EventSource interface {
+	Events() *Broadcaster
+}
+
+// Broadcaster 将变更事件广播给所有订阅者，并保留最近的 historySize 个事件用于恢复订阅。
+// Publish 不会阻塞：订阅者的缓冲区满了之后，该订阅会以 ErrSlowConsumer 关闭
+type Broadcaster struct {
+	mu         sync.Mutex
+	revision   int64
+	history    []Event // 按 revision 递增的环形缓冲区
+	historyLen int
+	bufferSize int
+	subs       map[*Subscription]struct{}
+	closed     bool
+}
+
+func NewBroadcaster(historySize, bufferSize int) *Broadcaster {
+	if historySize < 1 {
+		historySize = 1
+	}
+	if bufferSize < 1 {
+		bufferSize = 1
+	}
+	return &Broadcaster{
+		history:    make([]Event, historySize),
+		bufferSize: bufferSize,
+		subs:       make(map[*Subscription]struct{}),
+	}
+}
+
+// Revision 返回最后一个事件的 revision
+func (b *Broadcaster) Revision() int64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.revision
+}
+
+func (b *Broadcaster) Publish(typ EventType, user *v1.User) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return
+	}
+
+	b.revision++
+	event := Event{Revision: b.revision, Type: typ, User: user}
+	b.history[(b.revision-1)%int64(len(b.history))] = event
+	if b.historyLen < len(b.history) {
+		b.historyLen++
+	}
+
+	for sub := range b.subs {
+		select {
+		case sub.ch <- event:
+		default:
+			b.remove(sub, ErrSlowConsumer)
+		}
+	}
+}
+
+// Subscribe 订阅 revision 大于等于 start 的事件，start 为 0 时只订阅之后的新事件；
+// start 最大为当前 revision + 1，更大的 start 说明调用方的 revision 来自之前的进程，返回 ErrCompacted
+func (b *Broadcaster) Subscribe(start int64) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrBroadcasterClosed
+	}
+	if start > b.revision+1 {
+		return nil, ErrCompacted
+	}
+
+	var replay []Event
+	if start > 0 && start <= b.revision {
+		oldest := b.revision - int64(b.historyLen) + 1
+		if start < oldest {
+			return nil, ErrCompacted
+		}
+		for rev := start; rev <= b.revision; rev++ {
+			replay = append(replay, b.history[(rev-1)%int64(len(b.history))])
+		}
+	}
+
+	sub := &Subscription{ch: make(chan Event, b.bufferSize+len(replay)), b: b}
+	for _, event := range replay {
+		sub.ch <- event
+	}
+	b.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+// Close 关闭所有的订阅，之后的 Publish 和 Subscribe 都不再生效
+func (b *Broadcaster) Close() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.closed = true
+	for sub := range b.subs {
+		b.remove(sub, ErrBroadcasterClosed)
+	}
+}
+
+// remove 需要持有 b.mu
+func (b *Broadcaster) remove(sub *Subscription, err error) {
+	if _, ok := b.subs[sub]; !ok {
+		return
+	}
+	delete(b.subs, sub)
+	sub.err = err
+	close(sub.ch)
+}
+
+type Subscription struct {
+	ch  chan Event
+	err error
+	b   *Broadcaster
+}
+
+// Events 返回事件的 channel，订阅结束后 channel 被关闭，结束的原因由 Err 返回
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Err 返回订阅结束的原因，调用方主动 Close 时为 nil
+func (s *Subscription) Err() error {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	return s.err
+}
+
+func (s *Subscription) Close() {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	s.b.remove(s, nil)
+}
+
+// eventFactory 在写操作成功后产生变更事件
+type eventFactory struct {
+	Factory
+	events   *Broadcaster
+	deleteMu sync.Mutex // 删除操作互斥，保证删除前查询到的用户就是被删除的用户
+}
+
+// WithEvents 包装 f，f 上成功的 Create、Update、ChangePassword、Delete 和 DeleteCollection 都会发布到 b 中
+func WithEvents(f Factory, b *Broadcaster) Factory {
+	return &eventFactory{Factory: f, events: b}
+}
+
+func (f *eventFactory) Users() UserStore {
+	return &eventUsers{UserStore: f.Factory.Users(), events: f.events, deleteMu: &f.deleteMu}
+}
+
+func (f *eventFactory) Events() *Broadcaster {
+	return f.events
+}
+
+type eventUsers struct {
+	UserStore
+	events   *Broadcaster
+	deleteMu *sync.Mutex
+}
+
+func (u *eventUsers) Create(ctx context.Context, user *v1.User, opts metav1.CreateOptions) error {
+	if err := u.UserStore.Create(ctx, user, opts); err != nil {
+		return err
+	}
+	u.events.Publish(EventCreated, copyUser(user))
+	return nil
+}
+
+func (u *eventUsers) Update(ctx context.Context, user *v1.User, opts metav1.UpdateOptions) error {
+	if err := u.UserStore.Update(ctx, user, opts); err != nil {
+		return err
+	}
+	u.events.Publish(EventUpdated, copyUser(user))
+	return nil
+}
+
+func (u *eventUsers) ChangePassword(ctx context.Context, username, password string, opts metav1.UpdateOptions) error {
+	if err := u.UserStore.ChangePassword(ctx, username, password, opts); err != nil {
+		return err
+	}
+	user, err := u.UserStore.Get(ctx, username, metav1.GetOptions{})
+	if err != nil {
+		user = &v1.User{ObjectMeta: metav1.ObjectMeta{Name: username}}
+	}
+	u.events.Publish(EventUpdated, user)
+	return nil
+}
+
+func (u *eventUsers) Delete(ctx context.Context, username string, opts metav1.DeleteOptions) error {
+	return u.deleteAndPublish(ctx, []string{username}, func() error {
+		return u.UserStore.Delete(ctx, username, opts)
+	})
+}
+
+func (u *eventUsers) DeleteCollection(ctx context.Context, usernames []string, opts metav1.DeleteOptions) error {
+	return u.deleteAndPublish(ctx, usernames, func() error {
+		return u.UserStore.DeleteCollection(ctx, usernames, opts)
+	})
+}
+
+// deleteAndPublish 删除不存在的用户不会报错，所以删除前先查询，只为真正被删除的用户发布 EventDeleted；
+// 已经被软删除的用户查询不到，彻底删除时也不再发布
+func (u *eventUsers) deleteAndPublish(ctx context.Context, usernames []string, del func() error) error {
+	u.deleteMu.Lock()
+	defer u.deleteMu.Unlock()
+
+	var deleted []string
+	for _, username := range usernames {
+		_, err := u.UserStore.Get(ctx, username, metav1.GetOptions{})
+		if err == nil {
+			deleted = append(deleted, username)
+		} else if !errors.IsCode(err, code.ErrUserNotFound) {
+			return err
+		}
+	}
+	if err := del(); err != nil {
+		return err
+	}
+	for _, username := range deleted {
+		u.events.Publish(EventDeleted, &v1.User{ObjectMeta: metav1.ObjectMeta{Name: username}})
+	}
+	return nil
+}
+
+// copyUser 复制 user，避免调用方之后的修改影响已经发布的事件
+func copyUser(user *v1.User) *v1.User {
+	c := *user
+	return &c
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-examples-with-tests/database/v4/pkg/code"
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+	"github.com/marmotedu/errors"
+)
+
+func publish(b *Broadcaster, names ...string) {
+	for _, name := range names {
+		b.Publish(EventCreated, &v1.User{ObjectMeta: metav1.ObjectMeta{Name: name}})
+	}
+}
+
+func receive(t *testing.T, sub *Subscription, n int) []string {
+	var names []string
+	for i := 0; i < n; i++ {
+		event, ok := <-sub.Events()
+		if !ok {
+			t.Fatal("subscription closed:", sub.Err())
+		}
+		names = append(names, event.User.Name)
+	}
+	return names
+}
+
+func TestBroadcasterResume(t *testing.T) {
+	b := NewBroadcaster(3, 10)
+	publish(b, "a", "b", "c", "d")
+
+	sub, err := b.Subscribe(3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish(b, "e")
+	if names := receive(t, sub, 3); names[0] != "c" || names[1] != "d" || names[2] != "e" {
+		t.Fatal("should resume from revision 3, got:", names)
+	}
+
+	if _, err := b.Subscribe(1); err != ErrCompacted {
+		t.Fatal("revision 1 should be compacted, got:", err)
+	}
+
+	// 从下一个 revision 开始订阅，例如收到最后一个事件之后重新订阅
+	next, err := b.Subscribe(6)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish(b, "f")
+	if names := receive(t, next, 1); names[0] != "f" {
+		t.Fatal("should resume from revision 6, got:", names)
+	}
+
+	// 大于下一个 revision 的 start 来自之前的进程，不能静默地跳过事件
+	if _, err := b.Subscribe(8); err != ErrCompacted {
+		t.Fatal("future revision should be compacted, got:", err)
+	}
+}
+
+func TestBroadcasterSlowConsumer(t *testing.T) {
+	b := NewBroadcaster(10, 2)
+	slow, _ := b.Subscribe(0)
+	fast, _ := b.Subscribe(0)
+
+	publish(b, "a", "b")
+	receive(t, fast, 2)
+	publish(b, "c")
+	if _, ok := <-fast.Events(); !ok {
+		t.Fatal("fast subscriber should not be closed")
+	}
+
+	receive(t, slow, 2)
+	if _, ok := <-slow.Events(); ok || slow.Err() != ErrSlowConsumer {
+		t.Fatal("slow subscriber should be closed, got:", slow.Err())
+	}
+}
+
+func TestBroadcasterClose(t *testing.T) {
+	b := NewBroadcaster(10, 2)
+	sub, _ := b.Subscribe(0)
+	closed, _ := b.Subscribe(0)
+	closed.Close()
+	if _, ok := <-closed.Events(); ok || closed.Err() != nil {
+		t.Fatal("closed subscription should end without error, got:", closed.Err())
+	}
+
+	b.Close()
+	if _, ok := <-sub.Events(); ok || sub.Err() != ErrBroadcasterClosed {
+		t.Fatal("subscription should be closed with broadcaster, got:", sub.Err())
+	}
+	if _, err := b.Subscribe(0); err != ErrBroadcasterClosed {
+		t.Fatal("subscribe after close should fail, got:", err)
+	}
+}
+
+func TestWithEvents(t *testing.T) {
+	sqlite, err := NewSQLiteFactory("file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sqlite.Close()
+
+	b := NewBroadcaster(10, 10)
+	factory := WithEvents(sqlite, b)
+	ctx := context.TODO()
+	user := &v1.User{ObjectMeta: metav1.ObjectMeta{Name: "tom"}, Nickname: "tom", Password: "secret", Email: "tom@example.com"}
+	if err := factory.Users().Create(ctx, user, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// 失败的写操作不产生事件
+	if err := factory.Users().Create(ctx, user, metav1.CreateOptions{}); err == nil {
+		t.Fatal("duplicate user should fail")
+	}
+	if err := factory.Users().ChangePassword(ctx, "tom", "new", metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.Users().Delete(ctx, "tom", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	// 不存在或者已经被删除的用户不产生事件
+	if err := factory.Users().DeleteCollection(ctx, []string{"tom", "nobody"}, metav1.DeleteOptions{Unscoped: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := factory.Users().Delete(ctx, "nobody", metav1.DeleteOptions{}); !errors.IsCode(err, code.ErrUserNotFound) {
+		t.Fatal("delete missing user should be ErrUserNotFound, got:", err)
+	}
+
+	sub, err := factory.(EventSource).Events().Subscribe(1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []EventType{EventCreated, EventUpdated, EventDeleted}
+	for i, typ := range want {
+		event := <-sub.Events()
+		if event.Revision != int64(i+1) || event.Type != typ || event.User.Name != "tom" {
+			t.Fatalf("event %d want %v, got %+v", i, typ, event)
+		}
+	}
+	if revision := b.Revision(); revision != int64(len(want)) {
+		t.Fatalf("want %d events, got %d", len(want), revision)
+	}
+}
@@ -0,0 +1,254 @@
+// Package fake 提供 store.Factory 的内存实现，行为与 MySQL 的实现保持一致，用于不依赖数据库的测试
+package fake
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-examples-with-tests/database/v4/pkg/code"
+	"github.com/go-examples-with-tests/database/v4/store"
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	"github.com/marmotedu/component-base/pkg/auth"
+	"github.com/marmotedu/component-base/pkg/fields"
+	"github.com/marmotedu/component-base/pkg/labels"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+	"github.com/marmotedu/component-base/pkg/util/idutil"
+	"github.com/marmotedu/errors"
+)
+
+// defaultLimit 与 MySQL 实现中的一致
+const defaultLimit = 1000
+
+type datastore struct {
+	mu     sync.RWMutex
+	nextID uint64
+	users  map[string]*v1.User // key 为用户名
+	status map[string]int      // 0 表示已软删除
+}
+
+// NewFactory 创建一个空的内存 Factory
+func NewFactory() store.Factory {
+	return &datastore{
+		users:  make(map[string]*v1.User),
+		status: make(map[string]int),
+	}
+}
+
+func (ds *datastore) Users() store.UserStore {
+	return &users{ds}
+}
+
+func (ds *datastore) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (ds *datastore) Close() error {
+	return nil
+}
+
+type users struct {
+	ds *datastore
+}
+
+func (u *users) Create(ctx context.Context, user *v1.User, opts metav1.CreateOptions) error {
+	password, err := auth.Encrypt(user.Password)
+	if err != nil {
+		return errors.WithCode(code.ErrEncrypt, err.Error())
+	}
+
+	u.ds.mu.Lock()
+	defer u.ds.mu.Unlock()
+	if _, ok := u.ds.users[user.Name]; ok {
+		return errors.WithCode(code.ErrUserAlreadyExist, "user %s already exist", user.Name)
+	}
+
+	u.ds.nextID++
+	// 与 MySQL 的实现一样，updatedAt 精确到微秒
+	now := store.NextUpdatedAt(time.Time{})
+	user.ID = u.ds.nextID
+	user.InstanceID = idutil.GetInstanceID(user.ID, "user-")
+	user.Password = password
+	user.ExtendShadow = user.Extend.String()
+	user.CreatedAt, user.UpdatedAt = now, now
+	u.ds.users[user.Name] = clone(user)
+	u.ds.status[user.Name] = 1
+	return nil
+}
+
+func (u *users) Update(ctx context.Context, user *v1.User, opts metav1.UpdateOptions) error {
+	u.ds.mu.Lock()
+	defer u.ds.mu.Unlock()
+	stored, ok := u.get(user.Name)
+	if !ok {
+		return errors.WithCode(code.ErrUserNotFound, "user %s not found", user.Name)
+	}
+	if !stored.UpdatedAt.Equal(user.UpdatedAt) {
+		return errors.WithCode(code.ErrUserConflict, "user %s has been modified since %s", user.Name, user.UpdatedAt)
+	}
+
+	user.UpdatedAt = store.NextUpdatedAt(stored.UpdatedAt)
+	stored.Nickname = user.Nickname
+	stored.Email = user.Email
+	stored.Phone = user.Phone
+	stored.IsAdmin = user.IsAdmin
+	stored.Extend = user.Extend
+	stored.ExtendShadow = user.Extend.String()
+	stored.UpdatedAt = user.UpdatedAt
+	return nil
+}
+
+func (u *users) ChangePassword(ctx context.Context, username, password string, opts metav1.UpdateOptions) error {
+	hashed, err := auth.Encrypt(password)
+	if err != nil {
+		return errors.WithCode(code.ErrEncrypt, err.Error())
+	}
+
+	u.ds.mu.Lock()
+	defer u.ds.mu.Unlock()
+	stored, ok := u.get(username)
+	if !ok {
+		return errors.WithCode(code.ErrUserNotFound, "user %s not found", username)
+	}
+	stored.Password = hashed
+	stored.UpdatedAt = store.NextUpdatedAt(stored.UpdatedAt)
+	return nil
+}
+
+func (u *users) Delete(ctx context.Context, username string, opts metav1.DeleteOptions) error {
+	u.ds.mu.Lock()
+	defer u.ds.mu.Unlock()
+	if u.delete([]string{username}, opts) == 0 {
+		return errors.WithCode(code.ErrUserNotFound, "user %s not found", username)
+	}
+	return nil
+}
+
+func (u *users) DeleteCollection(ctx context.Context, usernames []string, opts metav1.DeleteOptions) error {
+	u.ds.mu.Lock()
+	defer u.ds.mu.Unlock()
+	u.delete(usernames, opts)
+	return nil
+}
+
+// delete 返回被删除的用户数，与 MySQL 一样，软删除不会再次匹配已经软删除的用户
+func (u *users) delete(usernames []string, opts metav1.DeleteOptions) int {
+	deleted := 0
+	for _, name := range usernames {
+		if _, ok := u.ds.users[name]; !ok {
+			continue
+		}
+		if opts.Unscoped {
+			delete(u.ds.users, name)
+			delete(u.ds.status, name)
+		} else if u.ds.status[name] == 1 {
+			u.ds.status[name] = 0
+		} else {
+			continue
+		}
+		deleted++
+	}
+	return deleted
+}
+
+func (u *users) Get(ctx context.Context, username string, opts metav1.GetOptions) (*v1.User, error) {
+	u.ds.mu.RLock()
+	defer u.ds.mu.RUnlock()
+	user, ok := u.get(username)
+	if !ok {
+		return nil, errors.WithCode(code.ErrUserNotFound, "user %s not found", username)
+	}
+	return clone(user), nil
+}
+
+func (u *users) List(ctx context.Context, opts metav1.ListOptions) (*v1.UserList, error) {
+	fieldSelector, err := fields.ParseSelector(opts.FieldSelector)
+	if err != nil {
+		return nil, errors.WithCode(code.ErrValidation, err.Error())
+	}
+	for _, r := range fieldSelector.Requirements() {
+		if _, ok := userFields(&v1.User{})[r.Field]; !ok {
+			return nil, errors.WithCode(code.ErrValidation, "field selector %q is not supported", r.Field)
+		}
+	}
+	labelSelector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, errors.WithCode(code.ErrValidation, err.Error())
+	}
+
+	u.ds.mu.RLock()
+	var items []*v1.User
+	for name, user := range u.ds.users {
+		if u.ds.status[name] != 1 || !fieldSelector.Matches(userFields(user)) {
+			continue
+		}
+		if !labelSelector.Matches(extendLabels(user.Extend)) {
+			continue
+		}
+		items = append(items, clone(user))
+	}
+	u.ds.mu.RUnlock()
+
+	// 与 MySQL 实现一致，按 id 倒序
+	sort.Slice(items, func(i, j int) bool { return items[i].ID > items[j].ID })
+	ret := &v1.UserList{Items: items}
+	ret.TotalCount = int64(len(items))
+
+	offset, limit := 0, defaultLimit
+	if opts.Offset != nil && *opts.Offset > 0 {
+		offset = int(*opts.Offset)
+	}
+	if opts.Limit != nil && *opts.Limit > 0 {
+		limit = int(*opts.Limit)
+	}
+	if offset >= len(items) {
+		ret.Items = nil
+		return ret, nil
+	}
+	ret.Items = items[offset:]
+	if limit < len(ret.Items) {
+		ret.Items = ret.Items[:limit]
+	}
+	return ret, nil
+}
+
+// get 返回未被软删除的用户，调用方需要持有锁
+func (u *users) get(username string) (*v1.User, bool) {
+	user, ok := u.ds.users[username]
+	if !ok || u.ds.status[username] != 1 {
+		return nil, false
+	}
+	return user, true
+}
+
+// userFields 是 FieldSelector 可以使用的字段，与 MySQL 实现中的 userColumns 保持一致
+func userFields(user *v1.User) fields.Set {
+	return fields.Set{
+		"name":     user.Name,
+		"nickname": user.Nickname,
+		"email":    user.Email,
+		"phone":    user.Phone,
+		"isAdmin":  strconv.Itoa(user.IsAdmin),
+	}
+}
+
+func extendLabels(extend metav1.Extend) labels.Set {
+	set := labels.Set{}
+	for k, v := range extend {
+		set[k] = fmt.Sprint(v)
+	}
+	return set
+}
+
+// clone 返回 user 的副本，避免调用方修改 store 中保存的数据
+func clone(user *v1.User) *v1.User {
+	c := *user
+	c.Extend = metav1.Extend{}
+	for k, v := range user.Extend {
+		c.Extend[k] = v
+	}
+	return &c
+}
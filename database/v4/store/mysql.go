@@ -1,8 +1,8 @@
 package store
 
 import (
+	"context"
 	"fmt"
-	"sync"
 
 	"github.com/go-examples-with-tests/database/v4/pkg"
 	"github.com/pkg/errors"
@@ -17,6 +17,14 @@ func (ds *datastore) Users() UserStore {
 	return newUsers(ds) // 用于和 MariaDB 交互
 }
 
+func (ds *datastore) Ping(ctx context.Context) error {
+	db, err := ds.db.DB()
+	if err != nil {
+		return errors.Wrap(err, "get gorm db instance failed")
+	}
+	return db.PingContext(ctx)
+}
+
 func (ds *datastore) Close() error {
 	db, err := ds.db.DB()
 	if err != nil {
@@ -25,39 +33,26 @@ func (ds *datastore) Close() error {
 	return db.Close()
 }
 
-var (
-	mysqlFactory Factory
-	once         sync.Once
-)
-
-func GetMySQLFactoryOr(opts *pkg.MySQLOptions) (Factory, error) {
-	if opts == nil && mysqlFactory == nil {
-		return nil, fmt.Errorf("failed to get mysql store fatory")
+// NewMySQLFactory 创建一个连接 MySQL 的 Factory，每次调用都会创建新的连接池，由调用方负责 Close
+func NewMySQLFactory(opts *pkg.MySQLOptions) (Factory, error) {
+	if opts == nil {
+		return nil, fmt.Errorf("failed to get mysql store fatory: nil options")
 	}
 
-	var err error
-	var dbIns *gorm.DB
-
-	// 此处是同步还是异步的？会阻塞吗？
-	once.Do(func() {
-		options := &pkg.Options{
-			Host:                  opts.Host,
-			Username:              opts.Username,
-			Password:              opts.Password,
-			Database:              opts.Database,
-			MaxIdleConnections:    opts.MaxIdleConnections,
-			MaxOpenConnections:    opts.MaxOpenConnections,
-			MaxConnectionLifeTime: opts.MaxConnectionLifeTime,
-			LogLevel:              opts.LogLevel,
-		}
-		// pkg.New --> *gorm.DB
-		dbIns, err = pkg.New(options)
-		mysqlFactory = &datastore{dbIns}
-	})
-
-	if mysqlFactory == nil || err != nil {
-		return nil, fmt.Errorf("failed to get mysql store fatory, mysqlFactory: %+v, error: %w", mysqlFactory, err)
+	options := &pkg.Options{
+		Host:                  opts.Host,
+		Username:              opts.Username,
+		Password:              opts.Password,
+		Database:              opts.Database,
+		MaxIdleConnections:    opts.MaxIdleConnections,
+		MaxOpenConnections:    opts.MaxOpenConnections,
+		MaxConnectionLifeTime: opts.MaxConnectionLifeTime,
+		LogLevel:              opts.LogLevel,
 	}
-
-	return mysqlFactory, nil
+	// pkg.New --> *gorm.DB
+	dbIns, err := pkg.New(options)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get mysql store fatory: %w", err)
+	}
+	return &datastore{dbIns}, nil
 }
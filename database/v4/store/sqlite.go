@@ -0,0 +1,51 @@
+package store
+
+import (
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// sqliteUser 是 SQLite 中 user 表的结构，与 IAM 中 MySQL 的 user 表保持一致
+type sqliteUser struct {
+	ID           uint64    `gorm:"primaryKey;autoIncrement;column:id"`
+	InstanceID   string    `gorm:"column:instanceID;type:varchar(32)"`
+	Name         string    `gorm:"column:name;type:varchar(45);not null;uniqueIndex"`
+	Status       int       `gorm:"column:status;not null;default:1"`
+	Nickname     string    `gorm:"column:nickname;type:varchar(30);not null"`
+	Password     string    `gorm:"column:password;type:varchar(255);not null"`
+	Email        string    `gorm:"column:email;type:varchar(256);not null"`
+	Phone        string    `gorm:"column:phone;type:varchar(20)"`
+	IsAdmin      int       `gorm:"column:isAdmin;not null;default:0"`
+	ExtendShadow string    `gorm:"column:extendShadow"`
+	CreatedAt    time.Time `gorm:"column:createdAt"`
+	UpdatedAt    time.Time `gorm:"column:updatedAt"`
+}
+
+func (sqliteUser) TableName() string {
+	return "user"
+}
+
+// NewSQLiteFactory 创建一个使用 SQLite 的 Factory 并自动建表，主要用于测试。
+// SQLite 同一时刻只允许一个写入，这里只使用一个连接，dsn 为 file::memory: 时也保证了所有的查询访问同一个内存数据库
+func NewSQLiteFactory(dsn string) (Factory, error) {
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{
+		Logger: logger.Default.LogMode(logger.Silent),
+	})
+	if err != nil {
+		return nil, err
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		return nil, err
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	if err := db.AutoMigrate(&sqliteUser{}); err != nil {
+		_ = sqlDB.Close()
+		return nil, err
+	}
+	return &datastore{db}, nil
+}
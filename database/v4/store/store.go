@@ -1,16 +1,11 @@
 package store
 
-var client Factory
+import "context"
 
+// Factory 由调用方创建后显式传给需要访问数据库的组件，例如 server.NewCache
 type Factory interface {
 	Users() UserStore
+	// Ping 检查数据库是否可以访问，用于健康检查
+	Ping(ctx context.Context) error
 	Close() error
 }
-
-func Client() Factory {
-	return client
-}
-
-func SetClient(factory Factory) {
-	client = factory
-}
@@ -2,13 +2,34 @@ package store
 
 import (
 	"context"
+	"fmt"
+	"strings"
+	"time"
 
+	"github.com/go-examples-with-tests/database/v4/pkg/code"
 	v1 "github.com/marmotedu/api/apiserver/v1"
+	"github.com/marmotedu/component-base/pkg/auth"
+	"github.com/marmotedu/component-base/pkg/fields"
+	"github.com/marmotedu/component-base/pkg/labels"
 	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+	"github.com/marmotedu/component-base/pkg/selection"
+	"github.com/marmotedu/component-base/pkg/util/idutil"
 	"github.com/marmotedu/errors"
 	"gorm.io/gorm"
 )
 
+// defaultLimit 是 List 未指定 limit 时，单次返回的最大记录数
+const defaultLimit = 1000
+
+// userColumns 是 List 中 FieldSelector 可以使用的字段，key 为字段名，value 为对应的列名
+var userColumns = map[string]string{
+	"name":     "name",
+	"nickname": "nickname",
+	"email":    "email",
+	"phone":    "phone",
+	"isAdmin":  "isAdmin",
+}
+
 type users struct {
 	db *gorm.DB
 }
@@ -17,31 +38,252 @@ func newUsers(ds *datastore) *users {
 	return &users{db: ds.db}
 }
 
+// Create 使用 bcrypt 加密密码后保存用户。
+// v1.User 的 AfterCreate 会通过 tx.Save 触发 BeforeUpdate 再次加密，所以这里跳过 gorm 的 hook，自行生成 instanceID
 func (u *users) Create(ctx context.Context, user *v1.User, opts metav1.CreateOptions) error {
+	password, err := auth.Encrypt(user.Password)
+	if err != nil {
+		return errors.WithCode(code.ErrEncrypt, err.Error())
+	}
+	user.Password = password
+	user.ExtendShadow = user.Extend.String()
+
+	err = u.db.WithContext(ctx).Session(&gorm.Session{SkipHooks: true}).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(user).Error; err != nil {
+			return err
+		}
+		user.InstanceID = idutil.GetInstanceID(user.ID, "user-")
+		return tx.Model(user).UpdateColumn("instanceID", user.InstanceID).Error
+	})
+	if err != nil {
+		if isDuplicateError(err) {
+			return errors.WithCode(code.ErrUserAlreadyExist, err.Error())
+		}
+		return errors.WithCode(code.ErrDatabase, err.Error())
+	}
 	return nil
 }
+
+// updatedAtPrecision 是 updatedAt 的精度，与 MySQL 中 timestamp(6) 的微秒一致
+const updatedAtPrecision = time.Microsecond
+
+// NextUpdatedAt 返回更新之后的 updatedAt，精确到微秒并且总是大于 prev，
+// 即使两次更新发生在同一微秒内或者时钟被回拨，版本也不会相同，乐观锁不会漏掉冲突
+func NextUpdatedAt(prev time.Time) time.Time {
+	now := time.Now().Truncate(updatedAtPrecision)
+	if !now.After(prev) {
+		now = prev.Truncate(updatedAtPrecision).Add(updatedAtPrecision)
+	}
+	return now
+}
+
+// Update 更新用户的基本信息（不包括用户名和密码），使用乐观锁：
+// user.UpdatedAt 是 Get 时读到的版本，期间用户被其他请求修改过，则返回 ErrUserConflict。
+// updatedAt 作为版本号需要精确到微秒，MySQL 中的列类型为 timestamp(6)
 func (u *users) Update(ctx context.Context, user *v1.User, opts metav1.UpdateOptions) error {
+	now := NextUpdatedAt(user.UpdatedAt)
+	result := u.db.WithContext(ctx).Table(user.TableName()).
+		Where("name = ? AND updatedAt = ? AND status = 1", user.Name, user.UpdatedAt).
+		Updates(map[string]interface{}{
+			"nickname":     user.Nickname,
+			"email":        user.Email,
+			"phone":        user.Phone,
+			"isAdmin":      user.IsAdmin,
+			"extendShadow": user.Extend.String(),
+			"updatedAt":    now,
+		})
+	if result.Error != nil {
+		if isDuplicateError(result.Error) {
+			return errors.WithCode(code.ErrUserAlreadyExist, result.Error.Error())
+		}
+		return errors.WithCode(code.ErrDatabase, result.Error.Error())
+	}
+
+	if result.RowsAffected == 0 {
+		var count int64
+		if err := u.db.WithContext(ctx).Table(user.TableName()).
+			Where("name = ? AND status = 1", user.Name).Count(&count).Error; err != nil {
+			return errors.WithCode(code.ErrDatabase, err.Error())
+		}
+		if count == 0 {
+			return errors.WithCode(code.ErrUserNotFound, "user %s not found", user.Name)
+		}
+		return errors.WithCode(code.ErrUserConflict, "user %s has been modified since %s", user.Name, user.UpdatedAt)
+	}
+
+	user.UpdatedAt = now
+	return nil
+}
+
+// ChangePassword 使用 bcrypt 加密新密码后更新用户的密码，旧密码的校验由调用方完成
+func (u *users) ChangePassword(ctx context.Context, username, password string, opts metav1.UpdateOptions) error {
+	hashed, err := auth.Encrypt(password)
+	if err != nil {
+		return errors.WithCode(code.ErrEncrypt, err.Error())
+	}
+	result := u.db.WithContext(ctx).Table((&v1.User{}).TableName()).
+		Where("name = ? AND status = 1", username).
+		Updates(map[string]interface{}{"password": hashed, "updatedAt": time.Now()})
+	if result.Error != nil {
+		return errors.WithCode(code.ErrDatabase, result.Error.Error())
+	}
+	if result.RowsAffected == 0 {
+		return errors.WithCode(code.ErrUserNotFound, "user %s not found", username)
+	}
 	return nil
 }
+
+// Delete 默认为软删除，将 status 置为 0；opts.Unscoped 为 true 时从数据库中删除记录（包括已经软删除的记录）。
+// 没有匹配的用户时返回 ErrUserNotFound
 func (u *users) Delete(ctx context.Context, username string, opts metav1.DeleteOptions) error {
+	deleted, err := u.delete(u.db.WithContext(ctx), []string{username}, opts)
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return errors.WithCode(code.ErrUserNotFound, "user %s not found", username)
+	}
 	return nil
 }
 
+// DeleteCollection 删除 usernames 中存在的用户，忽略不存在的用户
 func (u *users) DeleteCollection(ctx context.Context, usernames []string, opts metav1.DeleteOptions) error {
-	return nil
+	return u.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		_, err := u.delete(tx, usernames, opts)
+		return err
+	})
+}
+
+// delete 返回被删除的记录数
+func (u *users) delete(db *gorm.DB, usernames []string, opts metav1.DeleteOptions) (int64, error) {
+	var result *gorm.DB
+	if opts.Unscoped {
+		result = db.Where("name IN ?", usernames).Delete(&v1.User{})
+	} else {
+		result = db.Table((&v1.User{}).TableName()).Where("name IN ? AND status = 1", usernames).
+			Updates(map[string]interface{}{"status": 0, "updatedAt": time.Now()})
+	}
+	if result.Error != nil {
+		return 0, errors.WithCode(code.ErrDatabase, result.Error.Error())
+	}
+	return result.RowsAffected, nil
 }
 
 func (u *users) Get(ctx context.Context, username string, opts metav1.GetOptions) (*v1.User, error) {
 	user := &v1.User{}
-	err := u.db.Where("name = ?", username).First(&user).Error
+	err := u.db.WithContext(ctx).Where("name = ? AND status = 1", username).First(&user).Error
 	if err != nil {
 		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return nil, errors.WithCode(110001, err.Error())
+			return nil, errors.WithCode(code.ErrUserNotFound, err.Error())
 		}
-		return nil, errors.WithCode(100101, err.Error())
+		return nil, errors.WithCode(code.ErrDatabase, err.Error())
 	}
 	return user, nil
 }
+
+// List 按 id 倒序返回用户列表，支持 offset/limit 分页，FieldSelector 过滤 userColumns 中的字段，
+// LabelSelector 匹配用户的 Extend 字段，由于 Extend 以 JSON 的形式存储，标签过滤和分页在内存中完成
 func (u *users) List(ctx context.Context, opts metav1.ListOptions) (*v1.UserList, error) {
-	return nil, nil
+	query, args, err := fieldConditions(opts.FieldSelector)
+	if err != nil {
+		return nil, errors.WithCode(code.ErrValidation, err.Error())
+	}
+	selector, err := labels.Parse(opts.LabelSelector)
+	if err != nil {
+		return nil, errors.WithCode(code.ErrValidation, err.Error())
+	}
+	offset, limit := unpointer(opts.Offset, opts.Limit)
+
+	db := u.db.WithContext(ctx).Model(&v1.User{}).Where("status = 1")
+	if query != "" {
+		db = db.Where(query, args...)
+	}
+	db = db.Session(&gorm.Session{})
+	ret := &v1.UserList{}
+	if selector.Empty() {
+		if err := db.Count(&ret.TotalCount).Error; err != nil {
+			return nil, errors.WithCode(code.ErrDatabase, err.Error())
+		}
+		if err := db.Order("id desc").Offset(offset).Limit(limit).Find(&ret.Items).Error; err != nil {
+			return nil, errors.WithCode(code.ErrDatabase, err.Error())
+		}
+		return ret, nil
+	}
+
+	var items []*v1.User
+	if err := db.Order("id desc").Find(&items).Error; err != nil {
+		return nil, errors.WithCode(code.ErrDatabase, err.Error())
+	}
+	for _, item := range items {
+		if selector.Matches(extendLabels(item.Extend)) {
+			ret.Items = append(ret.Items, item)
+		}
+	}
+	ret.TotalCount = int64(len(ret.Items))
+	ret.Items = page(ret.Items, offset, limit)
+	return ret, nil
+}
+
+// fieldConditions 将 FieldSelector 转换为 SQL 的查询条件，例如 name=admin,phone!=1 --> name = ? AND phone <> ?
+func fieldConditions(selector string) (string, []interface{}, error) {
+	parsed, err := fields.ParseSelector(selector)
+	if err != nil {
+		return "", nil, err
+	}
+
+	var conditions []string
+	var args []interface{}
+	for _, r := range parsed.Requirements() {
+		column, ok := userColumns[r.Field]
+		if !ok {
+			return "", nil, fmt.Errorf("field selector %q is not supported", r.Field)
+		}
+		switch r.Operator {
+		case selection.Equals, selection.DoubleEquals:
+			conditions = append(conditions, column+" = ?")
+		case selection.NotEquals:
+			conditions = append(conditions, column+" <> ?")
+		default:
+			return "", nil, fmt.Errorf("operator %q is not supported", r.Operator)
+		}
+		args = append(args, r.Value)
+	}
+	return strings.Join(conditions, " AND "), args, nil
+}
+
+func extendLabels(extend metav1.Extend) labels.Set {
+	set := labels.Set{}
+	for k, v := range extend {
+		set[k] = fmt.Sprint(v)
+	}
+	return set
+}
+
+func unpointer(offset *int64, limit *int64) (int, int) {
+	o, l := 0, defaultLimit
+	if offset != nil && *offset > 0 {
+		o = int(*offset)
+	}
+	if limit != nil && *limit > 0 {
+		l = int(*limit)
+	}
+	return o, l
+}
+
+func page(items []*v1.User, offset, limit int) []*v1.User {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// isDuplicateError 判断是否为违反唯一索引的错误，兼容 MySQL 和 SQLite
+func isDuplicateError(err error) bool {
+	msg := err.Error()
+	return strings.Contains(msg, "Error 1062") || strings.Contains(msg, "Duplicate entry") ||
+		strings.Contains(msg, "UNIQUE constraint failed")
 }
@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-examples-with-tests/database/v4/pkg/code"
+	v1 "github.com/marmotedu/api/apiserver/v1"
+	metav1 "github.com/marmotedu/component-base/pkg/meta/v1"
+	"github.com/marmotedu/errors"
+)
+
+func TestFieldConditions(t *testing.T) {
+	query, args, err := fieldConditions("name=admin,phone!=123")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if query != "name = ? AND phone <> ?" || !reflect.DeepEqual(args, []interface{}{"admin", "123"}) {
+		t.Fatalf("got query: %s, args: %v", query, args)
+	}
+
+	if query, _, err := fieldConditions(""); err != nil || query != "" {
+		t.Fatalf("empty selector should have no condition, got: %s, %v", query, err)
+	}
+	if _, _, err := fieldConditions("password=123"); err == nil {
+		t.Fatal("password should not be a supported field")
+	}
+}
+
+func TestPage(t *testing.T) {
+	items := []*v1.User{{Nickname: "a"}, {Nickname: "b"}, {Nickname: "c"}}
+	if got := page(items, 1, 1); len(got) != 1 || got[0].Nickname != "b" {
+		t.Fatal("page(1, 1) should be [b], got:", got)
+	}
+	if got := page(items, 3, 1); got != nil {
+		t.Fatal("page out of range should be nil, got:", got)
+	}
+}
+
+func TestExtendLabels(t *testing.T) {
+	set := extendLabels(metav1.Extend{"team": "iam", "level": 3})
+	if set.Get("team") != "iam" || set.Get("level") != "3" {
+		t.Fatal("failed to convert extend to labels, got:", set)
+	}
+}
+
+func TestNextUpdatedAt(t *testing.T) {
+	future := time.Now().Add(time.Hour)
+	if next := NextUpdatedAt(future); !next.After(future) || next.Sub(future) > updatedAtPrecision {
+		t.Fatal("updatedAt should increase even if the clock goes backwards, got:", next)
+	}
+	if next := NextUpdatedAt(time.Time{}); next.Truncate(updatedAtPrecision) != next {
+		t.Fatal("updatedAt should be truncated to microseconds, got:", next)
+	}
+}
+
+func TestUsersSQLite(t *testing.T) {
+	factory, err := NewSQLiteFactory("file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer factory.Close()
+
+	ctx := context.TODO()
+	userStore := factory.Users()
+	user := &v1.User{ObjectMeta: metav1.ObjectMeta{Name: "tom"}, Nickname: "tom", Password: "secret", Email: "tom@example.com"}
+	if err := userStore.Create(ctx, user, metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := userStore.Create(ctx, user, metav1.CreateOptions{}); !errors.IsCode(err, code.ErrUserAlreadyExist) {
+		t.Fatal("duplicate user should be ErrUserAlreadyExist, got:", err)
+	}
+
+	got, err := userStore.Get(ctx, "tom", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.InstanceID == "" || got.Compare("secret") != nil {
+		t.Fatalf("instanceID should be set and password hashed with bcrypt, got: %s, %s", got.InstanceID, got.Password)
+	}
+
+	// 第二次使用旧版本更新时，乐观锁检测到冲突
+	stale := *got
+	got.Nickname = "tommy"
+	if err := userStore.Update(ctx, got, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := userStore.Update(ctx, &stale, metav1.UpdateOptions{}); !errors.IsCode(err, code.ErrUserConflict) {
+		t.Fatal("stale update should be ErrUserConflict, got:", err)
+	}
+
+	// 紧接着的两次更新发生在同一秒内，版本仍然不同
+	got, err = userStore.Get(ctx, "tom", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale = *got
+	if err := userStore.Update(ctx, got, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := userStore.Update(ctx, &stale, metav1.UpdateOptions{}); !errors.IsCode(err, code.ErrUserConflict) {
+		t.Fatal("update within the same second should be ErrUserConflict, got:", err)
+	}
+
+	if err := userStore.Delete(ctx, "tom", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := userStore.Get(ctx, "tom", metav1.GetOptions{}); !errors.IsCode(err, code.ErrUserNotFound) {
+		t.Fatal("soft deleted user should be ErrUserNotFound, got:", err)
+	}
+	if err := userStore.Delete(ctx, "tom", metav1.DeleteOptions{}); !errors.IsCode(err, code.ErrUserNotFound) {
+		t.Fatal("deleting soft deleted user again should be ErrUserNotFound, got:", err)
+	}
+	if err := userStore.Create(ctx, user, metav1.CreateOptions{}); !errors.IsCode(err, code.ErrUserAlreadyExist) {
+		t.Fatal("soft deleted user still keeps its name, got:", err)
+	}
+	if err := userStore.Delete(ctx, "tom", metav1.DeleteOptions{Unscoped: true}); err != nil {
+		t.Fatal(err)
+	}
+	if err := userStore.Create(ctx, user, metav1.CreateOptions{}); err != nil {
+		t.Fatal("name should be available after unscoped delete, got:", err)
+	}
+}
@@ -10,6 +10,7 @@ import (
 type UserStore interface {
 	Create(ctx context.Context, user *v1.User, opts metav1.CreateOptions) error
 	Update(ctx context.Context, user *v1.User, opts metav1.UpdateOptions) error
+	ChangePassword(ctx context.Context, username, password string, opts metav1.UpdateOptions) error
 	Delete(ctx context.Context, username string, opts metav1.DeleteOptions) error
 	DeleteCollection(ctx context.Context, usernames []string, opts metav1.DeleteOptions) error
 	Get(ctx context.Context, username string, opts metav1.GetOptions) (*v1.User, error)
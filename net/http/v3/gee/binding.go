@@ -0,0 +1,174 @@
+package gee
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// 绑定时使用的 struct tag，未设置时使用小写的字段名
+const (
+	formTag = "form" // query 参数和表单
+	uriTag  = "uri"  // 路由中的参数，例如 /users/:name
+)
+
+// ShouldBind 根据 Content-Type 选择解析方式：JSON 解析请求体，其余从 query 参数和表单中解析，解析后校验 binding tag
+func (ctx *Context) ShouldBind(obj interface{}) error {
+	if strings.HasPrefix(ctx.Request.Header.Get("Content-Type"), "application/json") {
+		return ctx.ShouldBindJSON(obj)
+	}
+	// ParseMultipartForm 同时会调用 ParseForm，非 multipart 请求返回 ErrNotMultipart
+	if err := ctx.Request.ParseMultipartForm(32 << 20); err != nil && err != http.ErrNotMultipart {
+		return err
+	}
+	return bindForm(obj, ctx.Request.Form, formTag)
+}
+
+func (ctx *Context) ShouldBindJSON(obj interface{}) error {
+	if ctx.Request.Body == nil {
+		return errors.New("invalid request: empty body")
+	}
+	if err := json.NewDecoder(ctx.Request.Body).Decode(obj); err != nil && err != io.EOF {
+		return err
+	}
+	return Validate(obj)
+}
+
+func (ctx *Context) ShouldBindQuery(obj interface{}) error {
+	return bindForm(obj, ctx.Request.URL.Query(), formTag)
+}
+
+func (ctx *Context) ShouldBindUri(obj interface{}) error {
+	params := make(map[string][]string, len(ctx.Params))
+	for _, param := range ctx.Params {
+		params[param.Key] = []string{param.Value}
+	}
+	return bindForm(obj, params, uriTag)
+}
+
+// Bind 系列方法在出错时通过 ctx.Error 返回 400，校验失败时响应中的 errors 列出所有不合法的字段
+func (ctx *Context) Bind(obj interface{}) error {
+	return ctx.failOnBind(ctx.ShouldBind(obj))
+}
+
+func (ctx *Context) BindJSON(obj interface{}) error {
+	return ctx.failOnBind(ctx.ShouldBindJSON(obj))
+}
+
+func (ctx *Context) BindQuery(obj interface{}) error {
+	return ctx.failOnBind(ctx.ShouldBindQuery(obj))
+}
+
+func (ctx *Context) BindUri(obj interface{}) error {
+	return ctx.failOnBind(ctx.ShouldBindUri(obj))
+}
+
+func (ctx *Context) failOnBind(err error) error {
+	if err == nil {
+		return nil
+	}
+	httpErr := NewHTTPError(http.StatusBadRequest, err.Error(), err)
+	if errs, ok := err.(ValidationErrors); ok {
+		httpErr.Details = errs
+	}
+	ctx.Error(httpErr)
+	return err
+}
+
+func bindForm(obj interface{}, form map[string][]string, tag string) error {
+	if err := mapForm(obj, form, tag); err != nil {
+		return err
+	}
+	return Validate(obj)
+}
+
+// mapForm 参考 reflect.Unpack，将 form 中的 key-value 写入 ptr 指向的 struct，匿名的内嵌 struct 会展开
+func mapForm(ptr interface{}, form map[string][]string, tag string) error {
+	v := reflect.ValueOf(ptr)
+	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("binding: %T is not a pointer to struct", ptr)
+	}
+	return mapStruct(v.Elem(), form, tag)
+}
+
+func mapStruct(ele reflect.Value, form map[string][]string, tag string) error {
+	for i := 0; i < ele.NumField(); i++ {
+		fieldInfo := ele.Type().Field(i)
+		field := ele.Field(i)
+		if fieldInfo.PkgPath != "" && !fieldInfo.Anonymous { // 未导出的字段
+			continue
+		}
+		if fieldInfo.Anonymous && field.Kind() == reflect.Struct {
+			if err := mapStruct(field, form, tag); err != nil {
+				return err
+			}
+			continue
+		}
+
+		name := fieldInfo.Tag.Get(tag)
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = strings.ToLower(fieldInfo.Name)
+		}
+		values, ok := form[name]
+		if !ok || len(values) == 0 {
+			continue
+		}
+
+		if field.Kind() == reflect.Slice {
+			slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+			for j, value := range values {
+				if err := populate(slice.Index(j), value); err != nil {
+					return fmt.Errorf("%s: %v", name, err)
+				}
+			}
+			field.Set(slice)
+			continue
+		}
+		if err := populate(field, values[0]); err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+	}
+	return nil
+}
+
+func populate(v reflect.Value, value string) error {
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(value)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i, err := strconv.ParseInt(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(i)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u, err := strconv.ParseUint(value, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(u)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(value, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported kind %s", v.Type())
+	}
+	return nil
+}
@@ -0,0 +1,118 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type address struct {
+	City string `json:"city" form:"city" binding:"required"`
+}
+
+type signup struct {
+	Name    string   `json:"name" form:"name" binding:"required,min=3,max=8"`
+	Email   string   `json:"email" form:"email" binding:"email"`
+	Age     int      `json:"age" form:"age" binding:"min=18,max=130"`
+	Role    string   `json:"role" form:"role" binding:"oneof=admin user"`
+	Tags    []string `json:"tags" form:"tag" binding:"max=2"`
+	Address *address `json:"address" binding:"required"`
+}
+
+func TestValidate(t *testing.T) {
+	valid := &signup{Name: "tom", Email: "tom@example.com", Age: 20, Role: "user", Address: &address{City: "sz"}}
+	if err := Validate(valid); err != nil {
+		t.Fatal(err)
+	}
+	// 非 required 的零值字段不检查其他规则
+	if err := Validate(&signup{Name: "tom", Address: &address{City: "sz"}}); err != nil {
+		t.Fatal("empty optional fields should be valid, got:", err)
+	}
+
+	invalid := &signup{Name: "to", Email: "tom", Age: 17, Role: "root", Tags: []string{"a", "b", "c"}, Address: &address{}}
+	err := Validate(invalid)
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		t.Fatal("error should be ValidationErrors, got:", err)
+	}
+	var fields []string
+	for _, e := range errs {
+		fields = append(fields, e.Field+":"+e.Rule)
+	}
+	want := []string{"Name:min", "Email:email", "Age:min", "Role:oneof", "Tags:max", "Address.City:required"}
+	if !reflect.DeepEqual(fields, want) {
+		t.Fatalf("invalid fields should be %v, got %v", want, fields)
+	}
+	if !strings.Contains(err.Error(), "Name must be at least 3 characters") {
+		t.Fatal("unexpected message:", err)
+	}
+}
+
+func TestBind(t *testing.T) {
+	engine := New()
+	var got signup
+	engine.POST("/users", func(ctx *Context) {
+		got = signup{}
+		if err := ctx.Bind(&got); err != nil {
+			return
+		}
+		ctx.String(http.StatusOK, "ok")
+	})
+	engine.GET("/users", func(ctx *Context) {
+		got = signup{Address: &address{City: "sz"}}
+		if err := ctx.BindQuery(&got); err != nil {
+			return
+		}
+		ctx.String(http.StatusOK, "ok")
+	})
+	engine.GET("/users/:name", func(ctx *Context) {
+		var uri struct {
+			Name string `uri:"name" binding:"min=3"`
+		}
+		if err := ctx.BindUri(&uri); err != nil {
+			return
+		}
+		got = signup{Name: uri.Name}
+		ctx.String(http.StatusOK, "ok")
+	})
+
+	tests := []struct {
+		method, target, contentType, body string
+		status                            int
+		want                              signup
+	}{
+		{"POST", "/users", "application/json", `{"name":"tom","email":"tom@example.com","age":20,"address":{"city":"sz"}}`,
+			http.StatusOK, signup{Name: "tom", Email: "tom@example.com", Age: 20, Address: &address{City: "sz"}}},
+		{"POST", "/users", "application/x-www-form-urlencoded", "name=tom&age=x",
+			http.StatusBadRequest, signup{Name: "tom"}},
+		{"GET", "/users?name=jerry&tag=a&tag=b&age=30", "", "",
+			http.StatusOK, signup{Name: "jerry", Age: 30, Tags: []string{"a", "b"}, Address: &address{City: "sz"}}},
+		{"GET", "/users/tom", "", "", http.StatusOK, signup{Name: "tom"}},
+		// uri 中的 name 不满足 min=3，got 保持上一次的结果
+		{"GET", "/users/to", "", "", http.StatusBadRequest, signup{Name: "tom"}},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest(tt.method, tt.target, strings.NewReader(tt.body))
+		req.Header.Set("Content-Type", tt.contentType)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != tt.status {
+			t.Fatalf("%s %s: status should be %d, got %d, %s", tt.method, tt.target, tt.status, w.Code, w.Body)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("%s %s: should bind %+v, got %+v", tt.method, tt.target, tt.want, got)
+		}
+	}
+
+	// 校验失败时 errors 中列出不合法的字段
+	req := httptest.NewRequest("POST", "/users", strings.NewReader(`{"name":"tom","age":1}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if !strings.Contains(w.Body.String(), `"field":"Age","rule":"min"`) ||
+		!strings.Contains(w.Body.String(), `"field":"Address","rule":"required"`) {
+		t.Fatal("response should list invalid fields, got:", w.Body)
+	}
+}
@@ -3,47 +3,90 @@ package gee
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
 )
 
 type H map[string]interface{}
 
+// abortIndex 大于任何 handler 链的长度，index 达到该值后 Next 不再执行后续的 HandleFunc
+const abortIndex = math.MaxInt32 / 2
+
+// Param 是路由中的一个参数，例如 /p/:name 中的 name
+type Param struct {
+	Key   string
+	Value string
+}
+
+// Params 按照在路由中出现的顺序保存参数，参数很少，顺序查找比 map 更快并且可以复用
+type Params []Param
+
+// Get 返回参数 name 的值
+func (ps Params) Get(name string) (string, bool) {
+	for _, param := range ps {
+		if param.Key == name {
+			return param.Value, true
+		}
+	}
+	return "", false
+}
+
+// Context 由 Engine 的 sync.Pool 复用，ServeHTTP 返回之后不能再使用，
+// 需要在其他 goroutine 中使用时先保存需要的字段
 type Context struct {
-	Writer  http.ResponseWriter
+	Writer  ResponseWriter
 	Request *http.Request
 
 	Path   string
 	Method string
 
-	Params map[string]string
+	Params Params
 
 	handlers []HandleFunc // middleware
 	index    int
 
-	StatusCode int
+	Errors []error // ctx.Error 记录的所有错误
 
+	writer responseWriter // Writer 指向 writer，复用时不需要重新分配
 	engine *Engine
 }
 
-func newContext(w http.ResponseWriter, req *http.Request) *Context {
-	return &Context{
-		Writer:  w,
-		Request: req,
-		Path:    req.URL.Path,
-		Method:  req.Method,
-		index:   -1,
-	}
+// reset 在 Context 从 pool 中取出后重置所有请求相关的字段
+func (ctx *Context) reset(w http.ResponseWriter, req *http.Request) {
+	ctx.writer.reset(w)
+	ctx.Writer = &ctx.writer
+	ctx.Request = req
+	ctx.Path = req.URL.Path
+	ctx.Method = req.Method
+	ctx.Params = ctx.Params[:0]
+	ctx.handlers = nil
+	ctx.index = -1
+	ctx.Errors = ctx.Errors[:0]
 }
 
 func (ctx *Context) Next() {
 	ctx.index++
-	s := len(ctx.handlers)
 	// 每调用一次 Next() 都对应一个 for 循环
-	for ; ctx.index < s; ctx.index++ {
+	for ; ctx.index < len(ctx.handlers); ctx.index++ {
 		ctx.handlers[ctx.index](ctx) // 若在此处继续调用 Next() 相当于在此处扩展开
 	}
 }
 
+// Abort 阻止执行后续的 HandleFunc，当前的 HandleFunc 和已经执行的 middleware 在 Next 之后的部分仍会继续执行
+func (ctx *Context) Abort() {
+	ctx.index = abortIndex
+}
+
+func (ctx *Context) IsAborted() bool {
+	return ctx.index >= abortIndex
+}
+
+// AbortWithStatus 设置状态码并调用 Abort
+func (ctx *Context) AbortWithStatus(code int) {
+	ctx.SetStatus(code)
+	ctx.Abort()
+}
+
 func (ctx *Context) PostForm(key string) string {
 	return ctx.Request.FormValue(key)
 }
@@ -58,9 +101,9 @@ func (ctx *Context) String(statusCode int, format string, values ...interface{})
 	ctx.Writer.Write([]byte(fmt.Sprintf(format, values...)))
 }
 
+// Fail 等价于 ctx.Error(NewHTTPError(code, err, nil))
 func (ctx *Context) Fail(code int, err string) {
-	ctx.index = len(ctx.handlers)
-	ctx.JSON(code, H{"message": err})
+	ctx.Error(NewHTTPError(code, err, nil))
 }
 
 func (ctx *Context) JSON(statusCode int, obj interface{}) {
@@ -80,7 +123,14 @@ func (ctx *Context) HTML(statusCode int, name string, data interface{}) {
 	}
 }
 
-func (ctx *Context) Data(statusCode int, data []byte) {
+// Data 写入 data，contentType 为空并且没有设置 Content-Type 时根据内容检测
+func (ctx *Context) Data(statusCode int, contentType string, data []byte) {
+	if contentType == "" && ctx.Writer.Header().Get("Content-Type") == "" {
+		contentType = http.DetectContentType(data)
+	}
+	if contentType != "" {
+		ctx.SetHeader("Content-Type", contentType)
+	}
 	ctx.SetStatus(statusCode)
 	ctx.Writer.Write(data)
 }
@@ -89,12 +139,12 @@ func (ctx *Context) SetHeader(key, value string) {
 	ctx.Writer.Header().Set(key, value)
 }
 
+// SetStatus 只记录状态码，写入响应体时才写入响应头
 func (ctx *Context) SetStatus(statusCode int) {
-	ctx.StatusCode = statusCode
 	ctx.Writer.WriteHeader(statusCode)
 }
 
 func (ctx *Context) Param(key string) string {
-	value := ctx.Params[key]
+	value, _ := ctx.Params.Get(key)
 	return value
 }
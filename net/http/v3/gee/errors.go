@@ -0,0 +1,86 @@
+package gee
+
+import (
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+)
+
+// HTTPError 是返回给调用方的错误，Message 会出现在响应中，Err 是内部的原因，只用于日志
+type HTTPError struct {
+	Code    int         // HTTP 状态码
+	Message string      // 返回给调用方的信息，为空时使用状态码对应的文本
+	Details interface{} // 可选的详细信息，例如 ValidationErrors
+	Err     error
+}
+
+// NewHTTPError 创建 HTTPError，err 可以为 nil
+func NewHTTPError(code int, message string, err error) *HTTPError {
+	return &HTTPError{Code: code, Message: message, Err: err}
+}
+
+func (e *HTTPError) Error() string {
+	if e.Err != nil {
+		return fmt.Sprintf("%d %s: %v", e.Code, e.message(), e.Err)
+	}
+	return fmt.Sprintf("%d %s", e.Code, e.message())
+}
+
+func (e *HTTPError) Unwrap() error {
+	return e.Err
+}
+
+func (e *HTTPError) message() string {
+	if e.Message != "" {
+		return e.Message
+	}
+	return http.StatusText(e.Code)
+}
+
+// toHTTPError 将 err 转换为 HTTPError，不是 HTTPError 的错误视为 500，原因不会出现在响应中
+func toHTTPError(err error) *HTTPError {
+	var httpErr *HTTPError
+	if errors.As(err, &httpErr) {
+		return httpErr
+	}
+	return &HTTPError{Code: http.StatusInternalServerError, Err: err}
+}
+
+// ErrorHandler 将 ctx.Error 记录的错误写入响应
+type ErrorHandler func(ctx *Context, err *HTTPError)
+
+var errorPage = template.Must(template.New("error").Parse(
+	`<!DOCTYPE html><html><head><title>{{.Code}} {{.Status}}</title></head>` +
+		`<body><h1>{{.Code}} {{.Status}}</h1><p>{{.Message}}</p></body></html>`))
+
+// DefaultErrorHandler 根据 Accept 返回 HTML 页面（浏览器）或者 JSON：{"code": 404, "message": "..."}，
+// 默认返回 JSON
+func DefaultErrorHandler(ctx *Context, err *HTTPError) {
+	if ctx.NegotiateFormat(MIMEJSON, MIMEHTML) == MIMEHTML {
+		ctx.SetHeader("Content-Type", "text/html; charset=utf-8")
+		ctx.SetStatus(err.Code)
+		errorPage.Execute(ctx.Writer, H{"Code": err.Code, "Status": http.StatusText(err.Code), "Message": err.message()})
+		return
+	}
+	body := H{"code": err.Code, "message": err.message()}
+	if err.Details != nil {
+		body["errors"] = err.Details
+	}
+	ctx.JSON(err.Code, body)
+}
+
+// Error 记录 err 并调用 Abort，响应还没有写入时使用 engine 的 ErrorHandler 写入响应；
+// 多次调用时只有第一个错误会写入响应，所有的错误都保存在 ctx.Errors 中，由 Logger 输出
+func (ctx *Context) Error(err error) {
+	ctx.Errors = append(ctx.Errors, err)
+	ctx.Abort()
+	if ctx.Writer.Written() {
+		return
+	}
+	handler := ctx.engine.ErrorHandler
+	if handler == nil {
+		handler = DefaultErrorHandler
+	}
+	handler(ctx, toHTTPError(err))
+}
@@ -0,0 +1,66 @@
+package gee
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestErrorHandler(t *testing.T) {
+	engine := New()
+	engine.Use(Recover())
+	engine.GET("/missing", func(ctx *Context) {
+		ctx.Error(NewHTTPError(http.StatusNotFound, "user not found", errors.New("record not found")))
+		// 第二个错误只记录，不写入响应
+		ctx.Error(errors.New("ignored"))
+		if len(ctx.Errors) != 2 {
+			t.Fatal("all errors should be collected, got:", ctx.Errors)
+		}
+	})
+	engine.GET("/internal", func(ctx *Context) {
+		ctx.Error(fmt.Errorf("query: %w", errors.New("connection refused")))
+	})
+	engine.GET("/panic", func(ctx *Context) {
+		panic("boom")
+	})
+	engine.GET("/fail", func(ctx *Context) {
+		ctx.Fail(http.StatusForbidden, "forbidden")
+	})
+
+	tests := []struct {
+		path, accept string
+		status       int
+		body         string
+	}{
+		{"/missing", "", http.StatusNotFound, `{"code":404,"message":"user not found"}`},
+		// 内部的原因不会出现在响应中
+		{"/internal", "", http.StatusInternalServerError, `{"code":500,"message":"Internal Server Error"}`},
+		{"/panic", "application/json", http.StatusInternalServerError, `{"code":500,"message":"Internal Server Error"}`},
+		{"/fail", "", http.StatusForbidden, `{"code":403,"message":"forbidden"}`},
+		{"/none", "", http.StatusNotFound, `{"code":404,"message":"404 page not found, Path:/none"}`},
+		{"/missing", "text/html,application/xhtml+xml", http.StatusNotFound, "<h1>404 Not Found</h1><p>user not found</p>"},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", tt.path, nil)
+		req.Header.Set("Accept", tt.accept)
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		if w.Code != tt.status || !strings.Contains(w.Body.String(), tt.body) {
+			t.Fatalf("Path:%s, want %d %s, got %d %s", tt.path, tt.status, tt.body, w.Code, w.Body)
+		}
+	}
+
+	var got *HTTPError
+	engine.ErrorHandler = func(ctx *Context, err *HTTPError) {
+		got = err
+		ctx.String(err.Code, "custom")
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/internal", nil))
+	if w.Body.String() != "custom" || got == nil || !strings.Contains(got.Error(), "connection refused") {
+		t.Fatal("custom ErrorHandler should receive the cause, got:", w.Body, got)
+	}
+}
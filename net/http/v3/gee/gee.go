@@ -5,19 +5,36 @@ import (
 	"log"
 	"net/http"
 	"path"
-	"strings"
+	"sync"
+	"time"
 )
 
 type HandleFunc func(ctx *Context)
 
 type (
 	Engine struct {
-		*RouterGroup                // 内嵌*RouterGroup，*Engin类型具有*RouterGroup的所有方法
-		groups       []*RouterGroup // 所有的路由分组
+		*RouterGroup // 内嵌*RouterGroup，*Engin类型具有*RouterGroup的所有方法
 		router       *router
+		pool         sync.Pool // 复用 Context
 
 		htmlTemplates *template.Template // for html render
 		funcMap       template.FuncMap
+
+		// ErrorHandler 将 ctx.Error 记录的错误写入响应，为 nil 时使用 DefaultErrorHandler
+		ErrorHandler ErrorHandler
+		// CheckOrigin 决定 ctx.WebSocket 是否接受请求的 Origin，为 nil 时只接受同源或者没有 Origin 的请求，
+		// 避免其他网站的页面借助用户的 cookie 建立连接
+		CheckOrigin func(r *http.Request) bool
+
+		// Run 系列方法创建 http.Server 时使用的配置，零值表示使用 net/http 的默认值（不超时）
+		ReadTimeout    time.Duration
+		WriteTimeout   time.Duration
+		IdleTimeout    time.Duration
+		MaxHeaderBytes int
+
+		mu      sync.Mutex
+		servers map[*http.Server]struct{} // 正在运行的 http.Server，Shutdown 时依次关闭
+		closed  bool
 	}
 
 	RouterGroup struct {
@@ -25,13 +42,16 @@ type (
 		prefix     string
 		parent     *RouterGroup // struct中不能定义相同类型的字段
 		middleware []HandleFunc // middleware处理
+		hasRoutes  bool         // 分组或者子分组已经注册了路由，之后不能再调用 Use
 	}
 )
 
 func New() *Engine {
 	engine := &Engine{router: newRouter()}
 	engine.RouterGroup = &RouterGroup{engine: engine}
-	engine.groups = []*RouterGroup{engine.RouterGroup}
+	engine.pool.New = func() interface{} {
+		return &Context{engine: engine}
+	}
 	return engine
 }
 
@@ -49,26 +69,79 @@ func (group *RouterGroup) Group(prefix string) *RouterGroup {
 		prefix: group.prefix + prefix,
 		parent: group,
 	}
-	group.engine.groups = append(group.engine.groups, newGroup)
 	return newGroup
 }
 
+// Use 添加分组的 middleware，路由注册时确定 middleware，所以必须在分组（以及子分组）注册路由之前调用，
+// 否则已经注册的路由不会执行新的 middleware，这里直接 panic；engine 的 middleware 同时用于未匹配的请求（404、405）
 func (group *RouterGroup) Use(middleware ...HandleFunc) {
+	if group.hasRoutes {
+		panic("Use must be called before registering routes in group \"" + group.prefix + "\"")
+	}
 	group.middleware = append(group.middleware, middleware...)
 }
 
-func (group *RouterGroup) GET(path string, handler HandleFunc) {
-	group.addRoute("GET", path, handler)
+func (group *RouterGroup) GET(path string, handlers ...HandleFunc) {
+	group.addRoute("GET", path, handlers)
+}
+
+func (group *RouterGroup) POST(path string, handlers ...HandleFunc) {
+	group.addRoute("POST", path, handlers)
+}
+
+func (group *RouterGroup) PUT(path string, handlers ...HandleFunc) {
+	group.addRoute("PUT", path, handlers)
+}
+
+func (group *RouterGroup) PATCH(path string, handlers ...HandleFunc) {
+	group.addRoute("PATCH", path, handlers)
+}
+
+func (group *RouterGroup) DELETE(path string, handlers ...HandleFunc) {
+	group.addRoute("DELETE", path, handlers)
+}
+
+// HEAD 未注册时，HEAD 请求由 GET 的 HandleFunc 处理，net/http 会丢弃响应体
+func (group *RouterGroup) HEAD(path string, handlers ...HandleFunc) {
+	group.addRoute("HEAD", path, handlers)
 }
 
-func (group *RouterGroup) POST(path string, handler HandleFunc) {
-	group.addRoute("POST", path, handler)
+// OPTIONS 未注册时，router 根据已注册的 method 自动返回 Allow
+func (group *RouterGroup) OPTIONS(path string, handlers ...HandleFunc) {
+	group.addRoute("OPTIONS", path, handlers)
 }
 
-func (group *RouterGroup) addRoute(method, component string, handler HandleFunc) {
+// Any 为 anyMethods 中的所有 method 注册同一个 HandleFunc
+func (group *RouterGroup) Any(path string, handlers ...HandleFunc) {
+	for _, method := range anyMethods {
+		group.addRoute(method, path, handlers)
+	}
+}
+
+// addRoute 注册路由，路由的 HandleFunc 依次为各级分组（从 engine 开始）的 middleware 和 handlers
+func (group *RouterGroup) addRoute(method, component string, handlers []HandleFunc) {
+	if len(handlers) == 0 {
+		panic("there must be at least one handler for " + method + " " + group.prefix + component)
+	}
 	pattern := group.prefix + component // 拼接 group.prefixe 和 component
 	log.Printf("component: %s, pattern: %s\n", component, pattern)
-	group.engine.router.addRoute(method, pattern, handler)
+	group.engine.router.addRoute(method, pattern, group.combineHandlers(handlers))
+	for g := group; g != nil; g = g.parent {
+		g.hasRoutes = true
+	}
+}
+
+// combineHandlers 返回新的 slice，避免多个路由共享同一个底层数组
+func (group *RouterGroup) combineHandlers(handlers []HandleFunc) []HandleFunc {
+	var groups []*RouterGroup
+	for g := group; g != nil; g = g.parent {
+		groups = append(groups, g)
+	}
+	var chain []HandleFunc
+	for i := len(groups) - 1; i >= 0; i-- {
+		chain = append(chain, groups[i].middleware...)
+	}
+	return append(chain, handlers...)
 }
 
 func (group *RouterGroup) createStaticHandler(relativePath string, fs http.FileSystem) HandleFunc {
@@ -99,18 +172,10 @@ func (engine *Engine) LoadHTMlGlob(pattern string) {
 }
 
 func (engine *Engine) ServeHTTP(w http.ResponseWriter, req *http.Request) {
-	var middlewares []HandleFunc
-	for _, group := range engine.groups {
-		if strings.HasPrefix(req.URL.Path, group.prefix) {
-			middlewares = append(middlewares, group.middleware...)
-		}
-	}
-	ctx := newContext(w, req)
-	ctx.handlers = middlewares
-	ctx.engine = engine
+	ctx := engine.pool.Get().(*Context)
+	ctx.reset(w, req)
 	engine.router.handle(ctx)
-}
-
-func (engine *Engine) Run(addr string) error {
-	return http.ListenAndServe(addr, engine)
+	// handler 只设置了状态码而没有写入响应体时，在这里写入响应头
+	ctx.Writer.WriteHeaderNow()
+	engine.pool.Put(ctx)
 }
@@ -0,0 +1,89 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// traceHandler 返回记录 HandleFunc 执行顺序的 middleware
+func traceHandler(steps *[]string, name string) HandleFunc {
+	return func(ctx *Context) {
+		*steps = append(*steps, name)
+	}
+}
+
+func TestRouteMiddleware(t *testing.T) {
+	var steps []string
+	engine := New()
+	engine.Use(traceHandler(&steps, "engine"))
+	v1 := engine.Group("/v1")
+	v1.Use(traceHandler(&steps, "v1"))
+	admin := v1.Group("/admin")
+	admin.Use(traceHandler(&steps, "admin"))
+	admin.GET("/users", traceHandler(&steps, "auth"), traceHandler(&steps, "users"))
+	engine.GET("/v10/users", traceHandler(&steps, "v10"))
+
+	tests := []struct {
+		path  string
+		steps string
+	}{
+		{"/v1/admin/users", "engine,v1,admin,auth,users"},
+		// /v10 不属于 /v1 分组
+		{"/v10/users", "engine,v10"},
+		// 未匹配的请求只经过 engine 的 middleware
+		{"/v1/none", "engine"},
+	}
+	for _, tt := range tests {
+		steps = nil
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", tt.path, nil))
+		if got := strings.Join(steps, ","); got != tt.steps {
+			t.Fatalf("Path:%s, steps should be %s, got %s", tt.path, tt.steps, got)
+		}
+	}
+}
+
+func TestUseAfterRoutes(t *testing.T) {
+	engine := New()
+	v1 := engine.Group("/v1")
+	v1.GET("/users", traceHandler(new([]string), "users"))
+	// 没有注册路由的分组仍然可以添加 middleware
+	engine.Group("/v2").Use(traceHandler(new([]string), "v2"))
+
+	for name, group := range map[string]*RouterGroup{"v1": v1, "engine": engine.RouterGroup} {
+		func() {
+			defer func() {
+				if err := recover(); err == nil {
+					t.Fatalf("Use on %s after registering routes should panic", name)
+				}
+			}()
+			group.Use(traceHandler(new([]string), name))
+		}()
+	}
+}
+
+func TestAbort(t *testing.T) {
+	var steps []string
+	engine := New()
+	engine.Use(func(ctx *Context) {
+		ctx.Next()
+		steps = append(steps, "after")
+		if !ctx.IsAborted() {
+			t.Fatal("context should be aborted")
+		}
+	})
+	engine.GET("/private", func(ctx *Context) {
+		ctx.AbortWithStatus(http.StatusUnauthorized)
+		steps = append(steps, "auth")
+	}, traceHandler(&steps, "handler"))
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest("GET", "/private", nil))
+	if w.Code != http.StatusUnauthorized {
+		t.Fatal("status should be 401, got:", w.Code)
+	}
+	if got := strings.Join(steps, ","); got != "auth,after" {
+		t.Fatal("handler should not run after abort, got:", got)
+	}
+}
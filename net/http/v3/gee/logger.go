@@ -11,6 +11,10 @@ func Logger() HandleFunc {
 
 		ctx.Next() // 把控制执行权交给下一个 HandleFunc 实例
 
-		log.Printf("[%v], %v, %v", ctx.StatusCode, ctx.Request.RequestURI, time.Since(start))
+		if len(ctx.Errors) > 0 {
+			log.Printf("[%v], %v, %v, errors: %v", ctx.Writer.Status(), ctx.Request.RequestURI, time.Since(start), ctx.Errors)
+			return
+		}
+		log.Printf("[%v], %v, %v", ctx.Writer.Status(), ctx.Request.RequestURI, time.Since(start))
 	}
 }
@@ -14,7 +14,7 @@ func Recover() HandleFunc {
 			if err := recover(); err != nil {
 				message := fmt.Sprintf("%s", err)
 				log.Printf("%s\n\n", trace(message))
-				ctx.Fail(http.StatusInternalServerError, "Internal Server Error")
+				ctx.Error(NewHTTPError(http.StatusInternalServerError, "", fmt.Errorf("panic: %s", message)))
 			}
 		}()
 		ctx.Next()
@@ -0,0 +1,223 @@
+package gee
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"google.golang.org/protobuf/proto"
+	"gopkg.in/yaml.v2"
+)
+
+// 常用的 MIME 类型，用于 Negotiate
+const (
+	MIMEJSON     = "application/json"
+	MIMEHTML     = "text/html"
+	MIMEXML      = "application/xml"
+	MIMEXML2     = "text/xml"
+	MIMEPlain    = "text/plain"
+	MIMEYAML     = "application/x-yaml"
+	MIMEPROTOBUF = "application/x-protobuf"
+)
+
+func (ctx *Context) IndentedJSON(statusCode int, obj interface{}) {
+	data, err := json.MarshalIndent(obj, "", "    ")
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Data(statusCode, MIMEJSON, data)
+}
+
+// callbackPattern 限制 JSONP 的回调函数名，避免注入任意的脚本
+var callbackPattern = regexp.MustCompile(`^[A-Za-z_$][A-Za-z0-9_$.]*$`)
+
+// JSONP 使用 query 参数 callback 作为回调函数名，没有 callback 时等价于 JSON
+func (ctx *Context) JSONP(statusCode int, obj interface{}) {
+	callback := ctx.Query("callback")
+	if callback == "" {
+		ctx.JSON(statusCode, obj)
+		return
+	}
+	if !callbackPattern.MatchString(callback) {
+		ctx.Fail(http.StatusBadRequest, "invalid callback: "+callback)
+		return
+	}
+	data, err := json.Marshal(obj)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Data(statusCode, "application/javascript", []byte(callback+"("+string(data)+");"))
+}
+
+func (ctx *Context) XML(statusCode int, obj interface{}) {
+	data, err := xml.Marshal(obj)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Data(statusCode, MIMEXML, data)
+}
+
+func (ctx *Context) YAML(statusCode int, obj interface{}) {
+	data, err := yaml.Marshal(obj)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Data(statusCode, MIMEYAML, data)
+}
+
+func (ctx *Context) ProtoBuf(statusCode int, msg proto.Message) {
+	data, err := proto.Marshal(msg)
+	if err != nil {
+		ctx.Error(err)
+		return
+	}
+	ctx.Data(statusCode, MIMEPROTOBUF, data)
+}
+
+// File 返回文件的内容，由 http.ServeFile 处理 Range、If-Modified-Since 等请求头
+func (ctx *Context) File(filepath string) {
+	http.ServeFile(ctx.Writer, ctx.Request, filepath)
+}
+
+// Attachment 和 File 一样，浏览器会以 filename 为名字下载文件
+func (ctx *Context) Attachment(filepath, filename string) {
+	ctx.SetHeader("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": filename}))
+	ctx.File(filepath)
+}
+
+// Stream 反复调用 step 写入响应并 flush，step 返回 false 时结束；客户端断开连接时返回 true
+func (ctx *Context) Stream(step func(w io.Writer) bool) bool {
+	done := ctx.Request.Context().Done()
+	for {
+		select {
+		case <-done:
+			return true
+		default:
+			keepOpen := step(ctx.Writer)
+			ctx.Writer.Flush()
+			if !keepOpen {
+				return false
+			}
+		}
+	}
+}
+
+// Negotiate 是 Context.Negotiate 可以返回的内容，Offered 按照优先顺序排列，
+// 每种类型的数据为 nil 时使用 Data，ProtoBufData 为 nil 时 Data 必须是 proto.Message
+type Negotiate struct {
+	Offered      []string
+	HTMLName     string
+	HTMLData     interface{}
+	JSONData     interface{}
+	XMLData      interface{}
+	YAMLData     interface{}
+	ProtoBufData proto.Message
+	Data         interface{}
+}
+
+// Negotiate 根据 Accept 从 config.Offered 中选择返回的格式，没有可以接受的格式时返回 406
+func (ctx *Context) Negotiate(statusCode int, config Negotiate) {
+	switch ctx.NegotiateFormat(config.Offered...) {
+	case MIMEJSON:
+		ctx.JSON(statusCode, pick(config.JSONData, config.Data))
+	case MIMEHTML:
+		ctx.HTML(statusCode, config.HTMLName, pick(config.HTMLData, config.Data))
+	case MIMEXML, MIMEXML2:
+		ctx.XML(statusCode, pick(config.XMLData, config.Data))
+	case MIMEYAML:
+		ctx.YAML(statusCode, pick(config.YAMLData, config.Data))
+	case MIMEPROTOBUF:
+		msg := config.ProtoBufData
+		if msg == nil {
+			var ok bool
+			if msg, ok = config.Data.(proto.Message); !ok {
+				ctx.Error(fmt.Errorf("negotiate: %T is not a proto.Message", config.Data))
+				return
+			}
+		}
+		ctx.ProtoBuf(statusCode, msg)
+	case MIMEPlain:
+		ctx.String(statusCode, "%v", config.Data)
+	default:
+		ctx.Fail(http.StatusNotAcceptable, "the accepted formats are not offered by the server")
+	}
+}
+
+func pick(data, fallback interface{}) interface{} {
+	if data != nil {
+		return data
+	}
+	return fallback
+}
+
+// NegotiateFormat 返回 offered 中 Accept 最优先接受的类型，没有 Accept 时返回 offered[0]，都不接受时返回 ""
+func (ctx *Context) NegotiateFormat(offered ...string) string {
+	if len(offered) == 0 {
+		return ""
+	}
+	accepts := parseAccept(ctx.Request.Header.Get("Accept"))
+	if len(accepts) == 0 {
+		return offered[0]
+	}
+	for _, accept := range accepts {
+		for _, offer := range offered {
+			if matchMIME(accept, offer) {
+				return offer
+			}
+		}
+	}
+	return ""
+}
+
+// parseAccept 解析 Accept 请求头，按照 q 值从高到低排序，q=0 表示不接受
+func parseAccept(header string) []string {
+	type item struct {
+		mime string
+		q    float64
+	}
+	var items []item
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		q := 1.0
+		if value, ok := params["q"]; ok {
+			if q, err = strconv.ParseFloat(value, 64); err != nil {
+				continue
+			}
+		}
+		if q > 0 {
+			items = append(items, item{mediaType, q})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+
+	accepts := make([]string, 0, len(items))
+	for _, item := range items {
+		accepts = append(accepts, item.mime)
+	}
+	return accepts
+}
+
+// matchMIME 判断 offer 是否满足 accept，accept 可以是 */* 或者 text/* 的形式
+func matchMIME(accept, offer string) bool {
+	if accept == "*/*" || accept == offer {
+		return true
+	}
+	if strings.HasSuffix(accept, "/*") {
+		return strings.HasPrefix(offer, accept[:len(accept)-1])
+	}
+	return false
+}
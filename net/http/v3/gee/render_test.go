@@ -0,0 +1,186 @@
+package gee
+
+import (
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/wrapperspb"
+)
+
+type book struct {
+	Title string `json:"title" xml:"title" yaml:"title"`
+}
+
+func serve(engine *Engine, target, accept string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest("GET", target, nil)
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	return w
+}
+
+func TestRender(t *testing.T) {
+	engine := New()
+	engine.GET("/xml", func(ctx *Context) { ctx.XML(http.StatusOK, book{"gee"}) })
+	engine.GET("/yaml", func(ctx *Context) { ctx.YAML(http.StatusOK, book{"gee"}) })
+	engine.GET("/indented", func(ctx *Context) { ctx.IndentedJSON(http.StatusOK, book{"gee"}) })
+	engine.GET("/jsonp", func(ctx *Context) { ctx.JSONP(http.StatusOK, book{"gee"}) })
+	engine.GET("/data", func(ctx *Context) { ctx.Data(http.StatusOK, "", []byte("<html></html>")) })
+
+	tests := []struct {
+		target      string
+		status      int
+		contentType string
+		body        string
+	}{
+		{"/xml", http.StatusOK, MIMEXML, "<book><title>gee</title></book>"},
+		{"/yaml", http.StatusOK, MIMEYAML, "title: gee\n"},
+		{"/indented", http.StatusOK, MIMEJSON, "{\n    \"title\": \"gee\"\n}"},
+		{"/jsonp?callback=app.show", http.StatusOK, "application/javascript", `app.show({"title":"gee"});`},
+		{"/jsonp?callback=alert(1)", http.StatusBadRequest, MIMEJSON, `"message":"invalid callback: alert(1)"`},
+		// 没有指定 Content-Type 时由 net/http 检测
+		{"/data", http.StatusOK, "text/html; charset=utf-8", "<html></html>"},
+	}
+	for _, tt := range tests {
+		w := serve(engine, tt.target, "")
+		if w.Code != tt.status || w.Header().Get("Content-Type") != tt.contentType || !strings.Contains(w.Body.String(), tt.body) {
+			t.Fatalf("%s: want %d %s %q, got %d %s %q", tt.target, tt.status, tt.contentType, tt.body,
+				w.Code, w.Header().Get("Content-Type"), w.Body)
+		}
+	}
+}
+
+func TestProtoBuf(t *testing.T) {
+	engine := New()
+	engine.GET("/pb", func(ctx *Context) { ctx.ProtoBuf(http.StatusOK, wrapperspb.String("gee")) })
+	w := serve(engine, "/pb", "")
+	msg := &wrapperspb.StringValue{}
+	if err := proto.Unmarshal(w.Body.Bytes(), msg); err != nil || msg.Value != "gee" {
+		t.Fatal("failed to decode protobuf:", err, msg)
+	}
+	if w.Header().Get("Content-Type") != MIMEPROTOBUF {
+		t.Fatal("unexpected content type:", w.Header().Get("Content-Type"))
+	}
+}
+
+func TestFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "hello.txt")
+	if err := ioutil.WriteFile(file, []byte("hello, gee"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := New()
+	engine.GET("/file", func(ctx *Context) { ctx.File(file) })
+	engine.GET("/download", func(ctx *Context) { ctx.Attachment(file, "报告.txt") })
+
+	req := httptest.NewRequest("GET", "/file", nil)
+	req.Header.Set("Range", "bytes=7-")
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, req)
+	if w.Code != http.StatusPartialContent || w.Body.String() != "gee" {
+		t.Fatalf("range request should return 206 gee, got %d %s", w.Code, w.Body)
+	}
+
+	w = serve(engine, "/download", "")
+	if w.Body.String() != "hello, gee" ||
+		w.Header().Get("Content-Disposition") != "attachment; filename*=utf-8''%E6%8A%A5%E5%91%8A.txt" {
+		t.Fatal("unexpected attachment:", w.Header().Get("Content-Disposition"), w.Body)
+	}
+}
+
+func TestStream(t *testing.T) {
+	engine := New()
+	engine.GET("/stream", func(ctx *Context) {
+		i := 0
+		ctx.Stream(func(w io.Writer) bool {
+			i++
+			io.WriteString(w, strings.Repeat("x", i))
+			return i < 3
+		})
+	})
+	w := serve(engine, "/stream", "")
+	if w.Body.String() != "xxxxxx" || !w.Flushed {
+		t.Fatal("stream should be flushed, got:", w.Body)
+	}
+}
+
+func TestNegotiate(t *testing.T) {
+	engine := New()
+	engine.GET("/book", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, Negotiate{
+			Offered:  []string{MIMEJSON, MIMEXML, MIMEYAML},
+			Data:     book{"gee"},
+			YAMLData: H{"title": "yaml"},
+		})
+	})
+
+	tests := []struct {
+		accept      string
+		status      int
+		contentType string
+	}{
+		{"", http.StatusOK, MIMEJSON},
+		{"*/*", http.StatusOK, MIMEJSON},
+		{"application/xml", http.StatusOK, MIMEXML},
+		{"text/html, application/x-yaml;q=0.9, application/json;q=0.8", http.StatusOK, MIMEYAML},
+		{"application/*;q=0.5, application/xml", http.StatusOK, MIMEXML},
+		{"application/json;q=0, text/plain", http.StatusNotAcceptable, MIMEJSON},
+	}
+	for _, tt := range tests {
+		w := serve(engine, "/book", tt.accept)
+		if w.Code != tt.status || w.Header().Get("Content-Type") != tt.contentType {
+			t.Fatalf("Accept:%s, want %d %s, got %d %s", tt.accept, tt.status, tt.contentType, w.Code, w.Header().Get("Content-Type"))
+		}
+	}
+	if w := serve(engine, "/book", "application/x-yaml"); w.Body.String() != "title: yaml\n" {
+		t.Fatal("YAMLData should be used, got:", w.Body)
+	}
+
+	// 提供了 protobuf 但是 ProtoBufData 为 nil，Data 也不是 proto.Message
+	if w := serve(engine, "/book", MIMEPROTOBUF); w.Code != http.StatusNotAcceptable {
+		t.Fatal("protobuf is not offered, got:", w.Code)
+	}
+}
+
+func TestNegotiateProtoBuf(t *testing.T) {
+	engine := New()
+	engine.GET("/book", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, Negotiate{
+			Offered:      []string{MIMEJSON, MIMEPROTOBUF},
+			Data:         book{"gee"},
+			ProtoBufData: wrapperspb.String("gee"),
+		})
+	})
+	engine.GET("/invalid", func(ctx *Context) {
+		ctx.Negotiate(http.StatusOK, Negotiate{Offered: []string{MIMEPROTOBUF}, Data: book{"gee"}})
+	})
+
+	w := serve(engine, "/book", "application/x-protobuf, application/json;q=0.5")
+	msg := &wrapperspb.StringValue{}
+	if w.Code != http.StatusOK || w.Header().Get("Content-Type") != MIMEPROTOBUF {
+		t.Fatalf("want protobuf, got %d %s", w.Code, w.Header().Get("Content-Type"))
+	}
+	if err := proto.Unmarshal(w.Body.Bytes(), msg); err != nil || msg.Value != "gee" {
+		t.Fatal("failed to decode protobuf:", err, msg)
+	}
+	if w := serve(engine, "/book", ""); w.Header().Get("Content-Type") != MIMEJSON {
+		t.Fatal("json should be preferred without Accept, got:", w.Header().Get("Content-Type"))
+	}
+	if w := serve(engine, "/invalid", MIMEPROTOBUF); w.Code != http.StatusInternalServerError {
+		t.Fatal("data that is not a proto.Message should fail, got:", w.Code)
+	}
+}
@@ -0,0 +1,103 @@
+package gee
+
+import (
+	"bufio"
+	"net"
+	"net/http"
+)
+
+// noWritten 表示响应头还没有写入
+const noWritten = -1
+
+// ResponseWriter 包装 http.ResponseWriter，WriteHeader 只记录状态码，第一次写入响应体（或者 WriteHeaderNow）时才真正写入响应头，
+// 所以 middleware 在 handler 写入响应体之前仍然可以修改状态码
+type ResponseWriter interface {
+	http.ResponseWriter
+	http.Flusher
+	http.Hijacker
+	http.Pusher
+
+	Status() int // 响应的状态码，默认为 200
+	Size() int   // 已经写入的响应体字节数，响应头还没有写入时为 -1
+	Written() bool
+	WriteHeaderNow() // 立即写入响应头
+}
+
+type responseWriter struct {
+	http.ResponseWriter
+	status int
+	size   int
+}
+
+func newResponseWriter(w http.ResponseWriter) *responseWriter {
+	writer := &responseWriter{}
+	writer.reset(w)
+	return writer
+}
+
+func (w *responseWriter) reset(writer http.ResponseWriter) {
+	w.ResponseWriter = writer
+	w.status = http.StatusOK
+	w.size = noWritten
+}
+
+// WriteHeader 响应头写入之后调用会被忽略，避免 net/http 的 superfluous WriteHeader 警告
+func (w *responseWriter) WriteHeader(code int) {
+	if code > 0 && !w.Written() {
+		w.status = code
+	}
+}
+
+func (w *responseWriter) WriteHeaderNow() {
+	if !w.Written() {
+		w.size = 0
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *responseWriter) Write(data []byte) (int, error) {
+	w.WriteHeaderNow()
+	n, err := w.ResponseWriter.Write(data)
+	w.size += n
+	return n, err
+}
+
+func (w *responseWriter) Status() int {
+	return w.status
+}
+
+func (w *responseWriter) Size() int {
+	return w.size
+}
+
+func (w *responseWriter) Written() bool {
+	return w.size != noWritten
+}
+
+func (w *responseWriter) Flush() {
+	w.WriteHeaderNow()
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// Hijack 之后连接由调用方管理，不再写入响应头
+func (w *responseWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	hijacker, ok := w.ResponseWriter.(http.Hijacker)
+	if !ok {
+		return nil, nil, http.ErrNotSupported
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err == nil && w.size < 0 {
+		w.size = 0
+	}
+	return conn, rw, err
+}
+
+// Push 只有 HTTP/2 连接支持，其他情况返回 http.ErrNotSupported
+func (w *responseWriter) Push(target string, opts *http.PushOptions) error {
+	if pusher, ok := w.ResponseWriter.(http.Pusher); ok {
+		return pusher.Push(target, opts)
+	}
+	return http.ErrNotSupported
+}
@@ -0,0 +1,74 @@
+package gee
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResponseWriter(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	w := newResponseWriter(recorder)
+	if w.Written() || w.Size() != -1 || w.Status() != http.StatusOK {
+		t.Fatal("new writer should not be written and status should be 200")
+	}
+
+	// 写入响应体之前可以修改状态码
+	w.WriteHeader(http.StatusNotFound)
+	w.WriteHeader(http.StatusCreated)
+	w.Write([]byte("hello"))
+	w.WriteHeader(http.StatusInternalServerError)
+	if recorder.Code != http.StatusCreated || w.Status() != http.StatusCreated || w.Size() != 5 {
+		t.Fatalf("status should be 201 and size 5, got %d %d %d", recorder.Code, w.Status(), w.Size())
+	}
+
+	w.Flush()
+	if !recorder.Flushed {
+		t.Fatal("flush should pass through")
+	}
+	if _, _, err := w.Hijack(); err != http.ErrNotSupported {
+		t.Fatal("recorder does not support hijack, got:", err)
+	}
+	if err := w.Push("/style.css", nil); err != http.ErrNotSupported {
+		t.Fatal("recorder does not support push, got:", err)
+	}
+}
+
+func TestStatusTracking(t *testing.T) {
+	var status, size int
+	engine := New()
+	engine.Use(func(ctx *Context) {
+		ctx.Next()
+		status, size = ctx.Writer.Status(), ctx.Writer.Size()
+	})
+	// 没有调用 SetStatus 直接写入
+	engine.GET("/write", func(ctx *Context) {
+		ctx.Writer.Write([]byte("hi"))
+	})
+	// 只设置状态码没有响应体
+	engine.GET("/created", func(ctx *Context) {
+		ctx.SetStatus(http.StatusCreated)
+	})
+	// 部分输出之后 Fail 只记录错误，不再写入响应
+	engine.GET("/partial", func(ctx *Context) {
+		ctx.String(http.StatusOK, "partial")
+		ctx.Fail(http.StatusInternalServerError, "failed")
+	})
+
+	tests := []struct {
+		path   string
+		status int
+		size   int
+	}{
+		{"/write", http.StatusOK, 2},
+		{"/created", http.StatusCreated, -1},
+		{"/partial", http.StatusOK, len("partial")},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest("GET", tt.path, nil))
+		if w.Code != tt.status || status != tt.status || size != tt.size {
+			t.Fatalf("Path:%s, want %d %d, got %d %d %d", tt.path, tt.status, tt.size, w.Code, status, size)
+		}
+	}
+}
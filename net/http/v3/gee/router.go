@@ -3,18 +3,23 @@ package gee
 import (
 	"log"
 	"net/http"
+	"sort"
 	"strings"
 )
 
+// anyMethods 是 Any 注册的 method
+var anyMethods = []string{
+	http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch,
+	http.MethodDelete, http.MethodHead, http.MethodOptions,
+}
+
 type router struct {
-	roots    map[string]*node      // roots key eg. roots["GET"] roots["POST"]
-	handlers map[string]HandleFunc // handlers key eg. handlers["GET-/p/:name/join"] handlers["POST-/p/:name"]
+	roots map[string]*node // roots key eg. roots["GET"] roots["POST"]，handler 链保存在 pattern 对应的节点上
 }
 
 func newRouter() *router {
 	return &router{
-		roots:    make(map[string]*node),
-		handlers: make(map[string]HandleFunc),
+		roots: make(map[string]*node),
 	}
 }
 
@@ -33,7 +38,7 @@ func parsePattern(pattern string) []string {
 	return vs
 }
 
-func (router *router) addRoute(method, pattern string, handler HandleFunc) {
+func (router *router) addRoute(method, pattern string, handlers []HandleFunc) {
 	log.Printf("Route %4s - %s", method, pattern)
 	if pattern == "" {
 		panic("router pattern is empty path")
@@ -45,49 +50,88 @@ func (router *router) addRoute(method, pattern string, handler HandleFunc) {
 		router.roots[method] = &node{}
 	}
 	// insert(pattern string, parts []string, height int)
-	router.roots[method].insert(pattern, parts, 0)
-
-	key := method + "-" + pattern
-	router.handlers[key] = handler
+	router.roots[method].insert(pattern, parts, 0).handlers = handlers
 }
 
+// getRoute 返回匹配的节点和 map 形式的参数，用于测试和非热点路径，处理请求时使用 find
 func (router *router) getRoute(method, path string) (*node, map[string]string) {
-	root, ok := router.roots[method]
-	if !ok {
+	var params Params
+	node := router.find(method, path, &params)
+	if node == nil {
 		return nil, nil
 	}
+	m := make(map[string]string, len(params))
+	for _, param := range params {
+		m[param.Key] = param.Value
+	}
+	return node, m
+}
 
+// find 查找 path 对应的节点，参数追加到 params 中，params 可以复用，查找过程不分配内存
+func (router *router) find(method, path string, params *Params) *node {
+	root, ok := router.roots[method]
+	if !ok {
+		return nil
+	}
 	// coding 锻炼写代码的逻辑，第一步做什么，第二步做什么...... Input/Output 分别是什么
 	// read code 掌握代码背后的设计（思路和艺术），为什么这么设计，如果是我，我该如何设计
-	parts := parsePattern(path)
-	node := root.search(parts, 0)
-	if node != nil {
-		params := make(map[string]string)
-		for index, item := range parsePattern(node.pattern) {
-			if item[0] == ':' {
-				params[item[1:]] = parts[index]
-			}
-			if item[0] == '*' && len(item) > 1 {
-				params[item[1:]] = strings.Join(parts[index:], "/")
-			}
+	return root.search(path, params)
+}
+
+// allowed 返回 path 能够匹配的所有 method，GET 可以匹配时同时允许 HEAD，任一 method 可以匹配时同时允许 OPTIONS
+func (router *router) allowed(path string) []string {
+	set := make(map[string]bool)
+	for method := range router.roots {
+		if node, _ := router.getRoute(method, path); node != nil {
+			set[method] = true
 		}
-		return node, params
 	}
+	if len(set) == 0 {
+		return nil
+	}
+	if set[http.MethodGet] {
+		set[http.MethodHead] = true
+	}
+	set[http.MethodOptions] = true
 
-	return nil, nil
+	methods := make([]string, 0, len(set))
+	for method := range set {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	return methods
 }
 
 func (router *router) handle(ctx *Context) {
-	log.Printf("Receive: %4s - %s", ctx.Method, ctx.Path)
-	if node, params := router.getRoute(ctx.Method, ctx.Path); node != nil {
-		key := ctx.Method + "-" + node.pattern
-		ctx.Params = params
-		// 将注册的 HandleFunc 添加到 Context 中
-		ctx.handlers = append(ctx.handlers, router.handlers[key])
+	node := router.find(ctx.Method, ctx.Path, &ctx.Params)
+	if node == nil && ctx.Method == http.MethodHead {
+		// HEAD 没有注册时使用 GET 的 HandleFunc
+		node = router.find(http.MethodGet, ctx.Path, &ctx.Params)
+	}
+
+	if node != nil {
+		// 注册时已经包含了分组的 middleware
+		ctx.handlers = node.handlers
+		ctx.Next()
+		return
+	}
+
+	// 未匹配的请求只经过 engine 的 middleware
+	ctx.handlers = append([]HandleFunc(nil), ctx.engine.middleware...)
+	if allow := router.allowed(ctx.Path); len(allow) > 0 {
+		// path 在其他 method 下存在：OPTIONS 直接返回 Allow，其余返回 405
+		ctx.handlers = append(ctx.handlers, func(ctx *Context) {
+			ctx.SetHeader("Allow", strings.Join(allow, ", "))
+			if ctx.Method == http.MethodOptions {
+				ctx.SetStatus(http.StatusNoContent)
+				return
+			}
+			ctx.Error(NewHTTPError(http.StatusMethodNotAllowed, "405 method not allowed, Path:"+ctx.Path, nil))
+		})
 	} else {
 		// 添加异常处理的 HandleFunc
 		ctx.handlers = append(ctx.handlers, func(ctx *Context) {
-			ctx.String(http.StatusNotFound, "404 page not found, Path:%s", ctx.Path)
+			ctx.Error(NewHTTPError(http.StatusNotFound, "404 page not found, Path:"+ctx.Path, nil))
 		})
 	}
 	ctx.Next()
@@ -0,0 +1,23 @@
+//go:build go1.18
+// +build go1.18
+
+package gee
+
+import "testing"
+
+func FuzzRoute(f *testing.F) {
+	patterns := []string{
+		"/", "/p/:lang", "/p/doc", "/p/:lang/intro", "/p/*filepath", "/p/doc/:page/edit",
+		"/assets/*filepath", "/hello/:name", "/hello/b/c",
+	}
+	router := newRouter()
+	for _, pattern := range patterns {
+		router.addRoute("GET", pattern, nil)
+	}
+	for _, path := range []string{"/", "/p/doc", "/p/doc/intro", "/p/doc/1/view", "/hello//b/c", "/assets/"} {
+		f.Add(path)
+	}
+	f.Fuzz(func(t *testing.T, path string) {
+		checkRoute(t, router, patterns, path)
+	})
+}
@@ -2,7 +2,11 @@ package gee
 
 import (
 	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 )
 
@@ -74,3 +78,351 @@ func TestGetRouteWithWildStar(t *testing.T) {
 	}
 	fmt.Printf("Path:%s, found: %s, params: %s\n", path, node.pattern, params["filepath"])
 }
+
+func TestMethodNotAllowed(t *testing.T) {
+	engine := New()
+	engine.GET("/users/:name", func(ctx *Context) {
+		ctx.String(http.StatusOK, "get %s", ctx.Param("name"))
+	})
+	engine.DELETE("/users/:name", func(ctx *Context) {
+		ctx.SetStatus(http.StatusNoContent)
+	})
+	engine.Any("/any", func(ctx *Context) {
+		ctx.String(http.StatusOK, "%s", ctx.Method)
+	})
+
+	tests := []struct {
+		method, path string
+		status       int
+		allow        string
+		body         string
+	}{
+		{method: "GET", path: "/users/tom", status: http.StatusOK, body: "get tom"},
+		{method: "DELETE", path: "/users/tom", status: http.StatusNoContent},
+		// HEAD 使用 GET 的 HandleFunc
+		{method: "HEAD", path: "/users/tom", status: http.StatusOK, body: "get tom"},
+		{method: "POST", path: "/users/tom", status: http.StatusMethodNotAllowed, allow: "DELETE, GET, HEAD, OPTIONS"},
+		{method: "OPTIONS", path: "/users/tom", status: http.StatusNoContent, allow: "DELETE, GET, HEAD, OPTIONS"},
+		{method: "PATCH", path: "/any", status: http.StatusOK, body: "PATCH"},
+		{method: "OPTIONS", path: "/any", status: http.StatusOK, body: "OPTIONS"},
+		{method: "POST", path: "/none", status: http.StatusNotFound},
+	}
+	for _, tt := range tests {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(tt.method, tt.path, nil))
+		if w.Code != tt.status {
+			t.Fatalf("%s %s: status should be %d, got %d", tt.method, tt.path, tt.status, w.Code)
+		}
+		if got := w.Header().Get("Allow"); got != tt.allow {
+			t.Fatalf("%s %s: Allow should be %q, got %q", tt.method, tt.path, tt.allow, got)
+		}
+		if tt.body != "" && w.Body.String() != tt.body {
+			t.Fatalf("%s %s: body should be %q, got %q", tt.method, tt.path, tt.body, w.Body.String())
+		}
+	}
+}
+
+func TestRoutePriority(t *testing.T) {
+	router := newRouter()
+	router.addRoute("GET", "/p/:lang", nil)
+	router.addRoute("GET", "/p/doc", nil)
+	router.addRoute("GET", "/p/:lang/intro", nil)
+	router.addRoute("GET", "/p/*filepath", nil)
+	router.addRoute("GET", "/p/doc/:page/edit", nil)
+
+	tests := []struct {
+		path, pattern string
+		params        map[string]string
+	}{
+		{"/p/doc", "/p/doc", map[string]string{}},
+		{"/p/go", "/p/:lang", map[string]string{"lang": "go"}},
+		// 静态的 doc 下没有 intro，回溯到 :lang
+		{"/p/doc/intro", "/p/:lang/intro", map[string]string{"lang": "doc"}},
+		{"/p/doc/1/edit", "/p/doc/:page/edit", map[string]string{"page": "1"}},
+		// 静态和参数都匹配失败，回溯到通配
+		{"/p/doc/1/view", "/p/*filepath", map[string]string{"filepath": "doc/1/view"}},
+	}
+	for _, tt := range tests {
+		node, params := router.getRoute("GET", tt.path)
+		if node == nil || node.pattern != tt.pattern || !reflect.DeepEqual(params, tt.params) {
+			t.Fatalf("Path:%s, want %s %v, got %v %v", tt.path, tt.pattern, tt.params, node, params)
+		}
+	}
+	if node, _ := router.getRoute("GET", "/p"); node != nil {
+		t.Fatal("/p is not a route, got:", node.pattern)
+	}
+}
+
+func TestRouteConflict(t *testing.T) {
+	tests := []struct {
+		exist, pattern string
+	}{
+		{"/p/:name", "/p/:lang"},
+		{"/p/:name/join", "/p/:lang/sell"},
+		{"/assets/*filepath", "/assets/*file"},
+		{"/p/:name", "/p/:name"},
+		{"/p/doc", "/p/doc/"},
+		{"/", "/p/:"},
+	}
+	for _, tt := range tests {
+		func() {
+			defer func() {
+				if err := recover(); err == nil {
+					t.Fatalf("%s should conflict with %s", tt.pattern, tt.exist)
+				}
+			}()
+			router := newRouter()
+			router.addRoute("GET", tt.exist, nil)
+			router.addRoute("GET", tt.pattern, nil)
+		}()
+	}
+
+	// 不同的 method 使用不同的 trie
+	router := newRouter()
+	router.addRoute("GET", "/p/:name", nil)
+	router.addRoute("POST", "/p/:lang", nil)
+}
+
+// matchPattern 是逐个 pattern 比较的参考实现，返回 pattern 每一段的优先级，0 静态，1 参数，2 通配
+func matchPattern(pattern, path string) ([]int, bool) {
+	patternParts := parsePattern(pattern)
+	// path 中以 * 开头的段没有特殊含义，不能使用 parsePattern
+	var parts []string
+	for _, part := range strings.Split(path, "/") {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	var rank []int
+	for index, item := range patternParts {
+		if item[0] == '*' {
+			return append(rank, 2), index < len(parts)
+		}
+		if index >= len(parts) || (item[0] != ':' && item != parts[index]) {
+			return nil, false
+		}
+		if item[0] == ':' {
+			rank = append(rank, 1)
+		} else {
+			rank = append(rank, 0)
+		}
+	}
+	return rank, len(patternParts) == len(parts)
+}
+
+// referenceRoute 返回所有匹配的 pattern 中，从左到右优先级最高的一个
+func referenceRoute(patterns []string, path string) string {
+	var best string
+	var bestRank []int
+	for _, pattern := range patterns {
+		rank, ok := matchPattern(pattern, path)
+		if !ok {
+			continue
+		}
+		if best == "" || lessRank(rank, bestRank) {
+			best, bestRank = pattern, rank
+		}
+	}
+	return best
+}
+
+func lessRank(a, b []int) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			return a[i] < b[i]
+		}
+	}
+	return len(a) < len(b)
+}
+
+func randomPath(r *rand.Rand, wild bool) string {
+	segments := []string{"a", "b", "c"}
+	var parts []string
+	for i, n := 0, r.Intn(4); i < n; i++ {
+		switch k := r.Intn(6); {
+		case wild && k == 0:
+			parts = append(parts, fmt.Sprintf(":p%d", i))
+		case wild && k == 1:
+			return "/" + strings.Join(append(parts, "*rest"), "/")
+		default:
+			parts = append(parts, segments[r.Intn(len(segments))])
+		}
+	}
+	return "/" + strings.Join(parts, "/")
+}
+
+// checkRoute 比较 router 和参考实现的匹配结果
+func checkRoute(t *testing.T, router *router, patterns []string, path string) {
+	want := referenceRoute(patterns, path)
+	node, _ := router.getRoute("GET", path)
+	var got string
+	if node != nil {
+		got = node.pattern
+	}
+	if got != want {
+		t.Fatalf("Path:%s, routes: %v, want %q, got %q", path, patterns, want, got)
+	}
+}
+
+func TestRouteRandom(t *testing.T) {
+	r := rand.New(rand.NewSource(1))
+	for i := 0; i < 200; i++ {
+		// 参数名由所在位置决定，通配名固定，生成的 pattern 之间不会冲突
+		router := newRouter()
+		registered := make(map[string]bool)
+		var patterns []string
+		for j := 0; j < 10; j++ {
+			pattern := randomPath(r, true)
+			if registered[pattern] {
+				continue
+			}
+			registered[pattern] = true
+			patterns = append(patterns, pattern)
+			router.addRoute("GET", pattern, nil)
+		}
+		for j := 0; j < 20; j++ {
+			checkRoute(t, router, patterns, randomPath(r, false))
+		}
+	}
+}
+
+func TestParams(t *testing.T) {
+	router := initTrieTree()
+	var params Params
+	if node := router.find("GET", "/assets//css/main.css/", &params); node == nil || node.pattern != "/assets/*filepath" {
+		t.Fatal("should match /assets/*filepath")
+	}
+	// 空的段被忽略，和 parsePattern 一致
+	if value, ok := params.Get("filepath"); !ok || value != "css/main.css" {
+		t.Fatal("filepath should be css/main.css, got:", value)
+	}
+
+	// 回溯时移除已经追加的参数
+	router.addRoute("GET", "/hello/:name/join", nil)
+	params = params[:0]
+	if node := router.find("GET", "/hello/b/c", &params); node == nil || len(params) != 0 {
+		t.Fatal("static route /hello/b/c should have no params, got:", params)
+	}
+}
+
+// nopWriter 不记录任何内容，避免测试 ServeHTTP 的内存分配时计入 ResponseRecorder 的分配
+type nopWriter struct{ header http.Header }
+
+func (w *nopWriter) Header() http.Header         { return w.header }
+func (w *nopWriter) Write(b []byte) (int, error) { return len(b), nil }
+func (w *nopWriter) WriteHeader(int)             {}
+
+func newBenchEngine() *Engine {
+	engine := New()
+	engine.Use(func(ctx *Context) { ctx.Next() })
+	body := []byte("ok")
+	engine.GET("/users/:name/repos/:repo", func(ctx *Context) {
+		if ctx.Param("repo") == "" {
+			panic("repo is required")
+		}
+		ctx.Writer.Write(body)
+	})
+	return engine
+}
+
+func TestServeHTTPAllocs(t *testing.T) {
+	engine := newBenchEngine()
+	w := &nopWriter{header: http.Header{}}
+	req := httptest.NewRequest("GET", "/users/tom/repos/gee", nil)
+	allocs := testing.AllocsPerRun(100, func() {
+		engine.ServeHTTP(w, req)
+	})
+	if allocs > 0 {
+		t.Fatal("ServeHTTP should not allocate, got:", allocs)
+	}
+}
+
+// legacyContext 是之前每个请求新建的 Context，参数保存在 map 中
+type legacyContext struct {
+	Writer     http.ResponseWriter
+	Request    *http.Request
+	Path       string
+	Method     string
+	Params     map[string]string
+	handlers   []HandleFunc
+	index      int
+	StatusCode int
+}
+
+// legacySink 让 legacyContext 逃逸到堆上，与之前的 newContext 一致
+var legacySink *legacyContext
+
+// legacySearch 是之前的查找方式：path 先切分为 parts，每一层都新建匹配的子节点列表
+func legacySearch(n *node, parts []string, height int) *node {
+	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+		if n.pattern == "" {
+			return nil
+		}
+		return n
+	}
+	matched := make([]*node, 0)
+	for _, child := range n.children {
+		if child.part == parts[height] {
+			matched = append(matched, child)
+		}
+	}
+	for _, child := range []*node{n.param, n.catchAll} {
+		if child != nil {
+			matched = append(matched, child)
+		}
+	}
+	for _, child := range matched {
+		if result := legacySearch(child, parts, height+1); result != nil {
+			return result
+		}
+	}
+	return nil
+}
+
+// BenchmarkLegacyServeHTTP 重现之前处理请求的方式：每个请求新建 Context，切分 path 查找路由，
+// 再切分 pattern 把参数保存到 map 中；handler 不在这里执行，与 BenchmarkServeHTTP 对比路由部分的内存分配
+func BenchmarkLegacyServeHTTP(b *testing.B) {
+	engine := newBenchEngine()
+	w := &nopWriter{header: http.Header{}}
+	req := httptest.NewRequest("GET", "/users/tom/repos/gee", nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		ctx := &legacyContext{Writer: w, Request: req, Path: req.URL.Path, Method: req.Method, index: -1}
+		parts := parsePattern(ctx.Path)
+		n := legacySearch(engine.router.roots[ctx.Method], parts, 0)
+		if n == nil {
+			b.Fatal("route not found")
+		}
+		params := make(map[string]string)
+		for index, item := range parsePattern(n.pattern) {
+			if item[0] == ':' {
+				params[item[1:]] = parts[index]
+			}
+			if item[0] == '*' && len(item) > 1 {
+				params[item[1:]] = strings.Join(parts[index:], "/")
+			}
+		}
+		ctx.Params, ctx.handlers = params, n.handlers
+		legacySink = ctx
+	}
+}
+
+func BenchmarkFind(b *testing.B) {
+	router := newBenchEngine().router
+	var params Params
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		params = params[:0]
+		router.find("GET", "/users/tom/repos/gee", &params)
+	}
+}
+
+func BenchmarkServeHTTP(b *testing.B) {
+	engine := newBenchEngine()
+	w := &nopWriter{header: http.Header{}}
+	req := httptest.NewRequest("GET", "/users/tom/repos/gee", nil)
+	b.ReportAllocs()
+	for i := 0; i < b.N; i++ {
+		engine.ServeHTTP(w, req)
+	}
+}
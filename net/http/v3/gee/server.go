@@ -0,0 +1,114 @@
+package gee
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+)
+
+// Run 监听 TCP 地址 addr 并处理请求，Shutdown 之后返回 nil
+func (engine *Engine) Run(addr string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return engine.RunListener(listener)
+}
+
+// RunTLS 监听 TCP 地址 addr 并处理 HTTPS 请求，可以和 Run 同时使用
+func (engine *Engine) RunTLS(addr, certFile, keyFile string) error {
+	listener, err := net.Listen("tcp", addr)
+	if err != nil {
+		return err
+	}
+	return engine.serve(listener, func(srv *http.Server) error {
+		return srv.ServeTLS(listener, certFile, keyFile)
+	})
+}
+
+// RunUnix 监听 unix socket，file 已经存在时先删除，例如上次异常退出留下的文件
+func (engine *Engine) RunUnix(file string) error {
+	if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	listener, err := net.Listen("unix", file)
+	if err != nil {
+		return err
+	}
+	return engine.RunListener(listener)
+}
+
+// RunListener 在 listener 上处理请求，返回时 listener 已经关闭
+func (engine *Engine) RunListener(listener net.Listener) error {
+	return engine.serve(listener, func(srv *http.Server) error {
+		return srv.Serve(listener)
+	})
+}
+
+// serve 创建 http.Server 并记录下来，run 在 listener 上处理请求，Shutdown 关闭之后 serve 返回 nil；
+// 调用 Shutdown 之后再调用 Run 系列方法会关闭 listener 并返回 http.ErrServerClosed
+func (engine *Engine) serve(listener net.Listener, run func(srv *http.Server) error) error {
+	srv := &http.Server{
+		Handler:        engine,
+		ReadTimeout:    engine.ReadTimeout,
+		WriteTimeout:   engine.WriteTimeout,
+		IdleTimeout:    engine.IdleTimeout,
+		MaxHeaderBytes: engine.MaxHeaderBytes,
+	}
+
+	engine.mu.Lock()
+	if engine.closed {
+		engine.mu.Unlock()
+		listener.Close()
+		return http.ErrServerClosed
+	}
+	if engine.servers == nil {
+		engine.servers = make(map[*http.Server]struct{})
+	}
+	engine.servers[srv] = struct{}{}
+	engine.mu.Unlock()
+
+	defer func() {
+		engine.mu.Lock()
+		delete(engine.servers, srv)
+		engine.mu.Unlock()
+	}()
+
+	if err := run(srv); err != http.ErrServerClosed {
+		return err
+	}
+	return nil
+}
+
+// Shutdown 停止接收新的连接，等待所有正在处理的请求结束后返回，ctx 结束时返回 ctx.Err()。
+// 所有 Run 系列方法启动的 http.Server 同时关闭，和 http.Server.Shutdown 一样，
+// 不会等待被 Hijack 的连接（例如 WebSocket）
+func (engine *Engine) Shutdown(ctx context.Context) error {
+	engine.mu.Lock()
+	engine.closed = true
+	servers := make([]*http.Server, 0, len(engine.servers))
+	for srv := range engine.servers {
+		servers = append(servers, srv)
+	}
+	engine.mu.Unlock()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, len(servers))
+	for _, srv := range servers {
+		wg.Add(1)
+		go func(srv *http.Server) {
+			defer wg.Done()
+			errs <- srv.Shutdown(ctx)
+		}(srv)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
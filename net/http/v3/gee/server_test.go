@@ -0,0 +1,110 @@
+package gee
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	started := make(chan struct{})
+	engine := New()
+	engine.GET("/slow", func(ctx *Context) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		ctx.String(http.StatusOK, "done")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	served := make(chan error, 1)
+	go func() { served <- engine.RunListener(listener) }()
+
+	type result struct {
+		body string
+		err  error
+	}
+	results := make(chan result, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String() + "/slow")
+		if err != nil {
+			results <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := ioutil.ReadAll(resp.Body)
+		results <- result{string(body), err}
+	}()
+
+	<-started
+	// Shutdown 等待正在处理的请求结束
+	if err := engine.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if r := <-results; r.err != nil || r.body != "done" {
+		t.Fatal("in-flight request should be finished, got:", r.body, r.err)
+	}
+	if err := <-served; err != nil {
+		t.Fatal("RunListener should return nil after shutdown, got:", err)
+	}
+	if err := engine.Run("127.0.0.1:0"); err != http.ErrServerClosed {
+		t.Fatal("Run after shutdown should return ErrServerClosed, got:", err)
+	}
+}
+
+func TestRunUnix(t *testing.T) {
+	dir, err := ioutil.TempDir("", "gee")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "gee.sock")
+	// 上次异常退出留下的文件
+	if err := ioutil.WriteFile(file, nil, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	engine := New()
+	engine.GET("/ping", func(ctx *Context) {
+		ctx.String(http.StatusOK, "pong")
+	})
+	served := make(chan error, 1)
+	go func() { served <- engine.RunUnix(file) }()
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", file)
+		},
+	}}
+	var resp *http.Response
+	for i := 0; i < 50; i++ {
+		if resp, err = client.Get("http://unix/ping"); err == nil {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "pong" {
+		t.Fatal("body should be pong, got:", string(body))
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := engine.Shutdown(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := <-served; err != nil {
+		t.Fatal(err)
+	}
+}
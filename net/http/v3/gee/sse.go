@@ -0,0 +1,112 @@
+package gee
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// SSE 是 Server-Sent Events 中的一个事件，Data 为 string 时原样发送，其他类型编码为 JSON
+type SSE struct {
+	Event string
+	ID    string
+	Retry time.Duration // 客户端断开后重连的间隔，0 表示不设置
+	Data  interface{}
+}
+
+// SSEvent 发送名为 name 的事件并立即 flush，name 为空时客户端按照 message 事件处理。
+// 长时间推送时需要关闭 Engine 的 WriteTimeout
+func (ctx *Context) SSEvent(name string, data interface{}) error {
+	return ctx.WriteSSE(SSE{Event: name, Data: data})
+}
+
+// WriteSSE 发送一个事件，第一次发送时写入 text/event-stream 的响应头
+func (ctx *Context) WriteSSE(event SSE) error {
+	data, ok := event.Data.(string)
+	if !ok {
+		encoded, err := json.Marshal(event.Data)
+		if err != nil {
+			return err
+		}
+		data = string(encoded)
+	}
+
+	var b strings.Builder
+	if event.ID != "" {
+		b.WriteString("id: " + sseEscape(event.ID) + "\n")
+	}
+	if event.Event != "" {
+		b.WriteString("event: " + sseEscape(event.Event) + "\n")
+	}
+	if event.Retry > 0 {
+		fmt.Fprintf(&b, "retry: %d\n", event.Retry.Milliseconds())
+	}
+	// 多行的数据每一行都需要 data: 前缀，客户端会用 \n 重新拼接
+	for _, line := range strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return ctx.writeSSE(b.String())
+}
+
+// SSEKeepAlive 发送注释行，客户端会忽略注释，用于防止代理因为连接空闲而断开
+func (ctx *Context) SSEKeepAlive() error {
+	return ctx.writeSSE(":keep-alive\n\n")
+}
+
+// SSEStream 发送 events 中的事件，直到 events 被关闭或者客户端断开，空闲 keepAlive 后发送 SSEKeepAlive（keepAlive <= 0 时不发送）；
+// 客户端断开或者发送失败时返回 true，发送失败的原因记录在 ctx.Errors 中
+func (ctx *Context) SSEStream(events <-chan SSE, keepAlive time.Duration) bool {
+	// keepAlive <= 0 时不发送 keep-alive，nil 的 channel 永远不会被选中
+	var tick <-chan time.Time
+	var ticker *time.Ticker
+	if keepAlive > 0 {
+		ticker = time.NewTicker(keepAlive)
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	done := ctx.Request.Context().Done()
+	for {
+		var err error
+		select {
+		case <-done:
+			return true
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			err = ctx.WriteSSE(event)
+			if ticker != nil {
+				ticker.Reset(keepAlive)
+			}
+		case <-tick:
+			err = ctx.SSEKeepAlive()
+		}
+		if err != nil {
+			ctx.Error(err)
+			return true
+		}
+	}
+}
+
+func (ctx *Context) writeSSE(data string) error {
+	if !ctx.Writer.Written() {
+		header := ctx.Writer.Header()
+		header.Set("Content-Type", "text/event-stream")
+		header.Set("Cache-Control", "no-cache")
+		header.Set("Connection", "keep-alive")
+		// 关闭 nginx 的缓冲，否则事件会被攒在一起发送
+		header.Set("X-Accel-Buffering", "no")
+	}
+	if _, err := ctx.Writer.Write([]byte(data)); err != nil {
+		return err
+	}
+	ctx.Writer.Flush()
+	return nil
+}
+
+// sseEscape 去掉 id 和 event 中的换行，避免破坏事件的格式
+func sseEscape(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
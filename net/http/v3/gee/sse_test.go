@@ -0,0 +1,80 @@
+package gee
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSSEvent(t *testing.T) {
+	engine := New()
+	engine.GET("/events", func(ctx *Context) {
+		ctx.SSEvent("greeting", "hello\nworld")
+		ctx.WriteSSE(SSE{ID: "2", Retry: time.Second, Data: H{"n": 2}})
+	})
+	w := serve(engine, "/events", "")
+	want := "event: greeting\ndata: hello\ndata: world\n\nid: 2\nretry: 1000\ndata: {\"n\":2}\n\n"
+	if w.Body.String() != want || w.Header().Get("Content-Type") != "text/event-stream" || !w.Flushed {
+		t.Fatalf("want %q, got %q %s", want, w.Body, w.Header().Get("Content-Type"))
+	}
+}
+
+func TestSSEStream(t *testing.T) {
+	events := make(chan SSE)
+	engine := New()
+	engine.GET("/stream", func(ctx *Context) {
+		ctx.SSEStream(events, 20*time.Millisecond)
+	})
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/stream")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	reader := bufio.NewReader(resp.Body)
+	readLine := func() string {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSuffix(line, "\n")
+	}
+
+	// 没有事件时定期发送 keep-alive，客户端可以立即收到，说明每次都 flush 了
+	if line := readLine(); line != ":keep-alive" {
+		t.Fatal("should receive keep-alive, got:", line)
+	}
+	readLine()
+	go func() { events <- SSE{Event: "tick", Data: "1"} }()
+	for line := readLine(); line != "event: tick"; line = readLine() {
+		if line != ":keep-alive" && line != "" {
+			t.Fatal("unexpected line:", line)
+		}
+	}
+	if line := readLine(); line != "data: 1" {
+		t.Fatal("should receive data, got:", line)
+	}
+	close(events)
+}
+
+func TestSSEStreamWithoutKeepAlive(t *testing.T) {
+	events := make(chan SSE, 2)
+	events <- SSE{Data: "1"}
+	events <- SSE{Data: "2"}
+	close(events)
+	engine := New()
+	engine.GET("/stream", func(ctx *Context) {
+		if ctx.SSEStream(events, 0) {
+			t.Error("closed events should return false")
+		}
+	})
+	w := serve(engine, "/stream", "")
+	if want := "data: 1\n\ndata: 2\n\n"; w.Body.String() != want {
+		t.Fatalf("want %q, got %q", want, w.Body)
+	}
+}
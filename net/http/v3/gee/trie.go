@@ -1,43 +1,75 @@
 package gee
 
-import "strings"
+import (
+	"fmt"
+	"strings"
+)
 
 // node constructor of router trie tree
+// 每个节点对应路由中的一段，子节点按照优先级分为三类：静态 > 参数（:name）> 通配（*filepath），
+// 查找时依次尝试，失败后回溯到下一类
 type node struct {
-	pattern  string  // 完整匹配路径
+	pattern  string  // 完整匹配路径，非空表示该节点是一个路由
 	part     string  // 当前节点的匹配内容
-	children []*node // 每个节点下的子节点
-	isWild   bool    // 是否包含通配符（* 和 :）
+	children []*node // 静态子节点
+	param    *node   // 参数子节点，同一位置只能有一个，例如 :name
+	catchAll *node   // 通配子节点，同一位置只能有一个，例如 *filepath
+
+	handlers []HandleFunc // 路由的 handler 链，注册时已经包含了分组的 middleware
 }
 
-// insert trie tree node with pattern
-func (n *node) insert(pattern string, parts []string, height int) {
+// insert trie tree node with pattern，返回 pattern 对应的节点
+// 同一个 pattern 重复注册，或者同一位置的参数名、通配名不一致时 panic
+func (n *node) insert(pattern string, parts []string, height int) *node {
 	//TEST CASE: /p/:name/join [p, :name, join] 0
 	if len(parts) == height {
+		if n.pattern != "" {
+			panic(fmt.Sprintf("route %s conflicts with existing route %s", pattern, n.pattern))
+		}
 		n.pattern = pattern
-		return
+		return n
 	}
 
 	// TDD
 	// 0 --> p
 	// 1 --> :name
 	// 2 --> join
-
-	//FIXME /p/:name/join /p/:time/sell
-	//FIXME /p/:name /p/michoi
 	part := parts[height]
-	child := n.matchChild(part)
-	if child == nil {
-		// :name *filepath 存入 node.part
-		child = &node{part: part, isWild: part[0] == ':' || part[0] == '*'}
-		n.children = append(n.children, child)
+	var child *node
+	switch part[0] {
+	case ':':
+		if len(part) == 1 {
+			panic(fmt.Sprintf("param in route %s must have a name", pattern))
+		}
+		child = n.wildChild(&n.param, pattern, part)
+	case '*':
+		// parsePattern 保证通配只会是最后一段
+		child = n.wildChild(&n.catchAll, pattern, part)
+	default:
+		child = n.staticChild(part)
+		if child == nil {
+			child = &node{part: part}
+			n.children = append(n.children, child)
+		}
 	}
-	child.insert(pattern, parts, height+1)
+	return child.insert(pattern, parts, height+1)
 }
 
-func (n *node) search(parts []string, height int) *node {
-	// just for * only once
-	if len(parts) == height || strings.HasPrefix(n.part, "*") {
+// wildChild 返回参数或通配子节点，不存在时创建；/p/:lang 和 /p/:name 无法区分，视为冲突
+func (n *node) wildChild(slot **node, pattern, part string) *node {
+	if *slot == nil {
+		*slot = &node{part: part}
+	} else if (*slot).part != part {
+		panic(fmt.Sprintf("%s in route %s conflicts with existing wildcard %s", part, pattern, (*slot).part))
+	}
+	return *slot
+}
+
+// search 在 path 上直接查找，不切分 path，匹配到的参数追加到 params 中，回溯时移除；
+// path 是剩余的路径，和 parsePattern 一样忽略空的段
+func (n *node) search(path string, params *Params) *node {
+	path = strings.TrimLeft(path, "/")
+	if path == "" {
 		if n.pattern == "" {
 			// middle path of route，并不是一个路由
 			return nil
@@ -45,35 +77,52 @@ func (n *node) search(parts []string, height int) *node {
 		return n
 	}
 
-	part := parts[height]
-	child := n.matchChildren(part)
-	// children 为 []*node，是否存在多个匹配情况
-	for _, item := range child {
-		result := item.search(parts, height+1)
-		if child != nil {
+	part, rest := path, ""
+	if index := strings.IndexByte(path, '/'); index >= 0 {
+		part, rest = path[:index], path[index:]
+	}
+	if child := n.staticChild(part); child != nil {
+		if result := child.search(rest, params); result != nil {
 			return result
 		}
 	}
+	if n.param != nil {
+		*params = append(*params, Param{Key: n.param.part[1:], Value: part})
+		if result := n.param.search(rest, params); result != nil {
+			return result
+		}
+		*params = (*params)[:len(*params)-1]
+	}
+	// 通配匹配剩余的所有段（至少一段）
+	if n.catchAll != nil {
+		if len(n.catchAll.part) > 1 {
+			*params = append(*params, Param{Key: n.catchAll.part[1:], Value: cleanRest(path)})
+		}
+		return n.catchAll
+	}
 	return nil
 }
 
-// matchChild matches children of node to find match one
-func (n *node) matchChild(path string) *node {
-	for _, ele := range n.children {
-		if ele.part == path || ele.isWild {
-			return ele
+// cleanRest 去掉剩余路径中空的段，例如 a//b/ --> a/b，大多数路径不需要处理，直接返回原字符串
+func cleanRest(path string) string {
+	if !strings.Contains(path, "//") && !strings.HasSuffix(path, "/") {
+		return path
+	}
+	var parts []string
+	for _, part := range strings.Split(path, "/") {
+		if part != "" {
+			parts = append(parts, part)
 		}
 	}
-	return nil
+	return strings.Join(parts, "/")
 }
 
-// matchChildren matches all children, and return all nodes
-func (n *node) matchChildren(path string) []*node {
-	nodes := make([]*node, 0)
-	for _, ele := range n.children {
-		if ele.part == path || ele.isWild {
-			nodes = append(nodes, ele)
+// staticChild 返回 part 完全相同的静态子节点
+func (n *node) staticChild(part string) *node {
+	for _, child := range n.children {
+		if child.part == part {
+			return child
 		}
 	}
-	return nodes
+	return nil
 }
@@ -0,0 +1,205 @@
+package gee
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// validateTag 声明字段的校验规则，多个规则以逗号分隔，例如 `binding:"required,min=3,max=20"`；
+// 除 required 外，字段为零值时不检查其他规则
+const validateTag = "binding"
+
+// FieldError 描述一个不合法的字段，Field 为字段在 struct 中的路径，例如 Address.City
+type FieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// ValidationErrors 包含所有不合法的字段
+type ValidationErrors []*FieldError
+
+func (errs ValidationErrors) Error() string {
+	messages := make([]string, 0, len(errs))
+	for _, err := range errs {
+		messages = append(messages, err.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// rule 检查 v 是否满足规则，不满足时返回错误的描述，param 为 = 之后的参数
+type rule func(v reflect.Value, param string) string
+
+var rules = map[string]rule{
+	"required": func(v reflect.Value, param string) string {
+		if isEmpty(v) {
+			return "is required"
+		}
+		return ""
+	},
+	"min": func(v reflect.Value, param string) string {
+		if compare(v, param) < 0 {
+			return "must be at least " + param + unit(v)
+		}
+		return ""
+	},
+	"max": func(v reflect.Value, param string) string {
+		if compare(v, param) > 0 {
+			return "must be at most " + param + unit(v)
+		}
+		return ""
+	},
+	"email": func(v reflect.Value, param string) string {
+		if v.Kind() == reflect.String {
+			if addr, err := mail.ParseAddress(v.String()); err == nil && addr.Address == v.String() {
+				return ""
+			}
+		}
+		return "must be a valid email address"
+	},
+	"oneof": func(v reflect.Value, param string) string {
+		value := fmt.Sprint(v.Interface())
+		for _, item := range strings.Fields(param) {
+			if item == value {
+				return ""
+			}
+		}
+		return "must be one of [" + param + "]"
+	},
+}
+
+// Validate 按照 binding tag 校验 obj 指向的 struct，内嵌和嵌套的 struct 会递归校验，
+// 返回的 ValidationErrors 包含所有不合法的字段；tag 中的规则不存在或者参数不合法时 panic
+func Validate(obj interface{}) error {
+	v := reflect.ValueOf(obj)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	var errs ValidationErrors
+	validateStruct(v, "", &errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func validateStruct(v reflect.Value, prefix string, errs *ValidationErrors) {
+	for i := 0; i < v.NumField(); i++ {
+		fieldInfo := v.Type().Field(i)
+		if fieldInfo.PkgPath != "" && !fieldInfo.Anonymous { // 未导出的字段
+			continue
+		}
+		raw := v.Field(i)
+		// 非 nil 的指针按照指向的值校验，nil 指针视为零值
+		field := raw
+		for field.Kind() == reflect.Ptr && !field.IsNil() {
+			field = field.Elem()
+		}
+		name := prefix + fieldInfo.Name
+		if fieldInfo.Anonymous {
+			name = prefix
+		}
+
+		for _, item := range splitRules(fieldInfo.Tag.Get(validateTag)) {
+			key, param := item, ""
+			if index := strings.Index(item, "="); index >= 0 {
+				key, param = item[:index], item[index+1:]
+			}
+			check, ok := rules[key]
+			if !ok {
+				panic(fmt.Sprintf("binding: unknown rule %q on field %s", key, name))
+			}
+			target := field
+			if key == "required" {
+				target = raw
+			} else if isEmpty(raw) {
+				continue
+			}
+			if message := check(target, param); message != "" {
+				*errs = append(*errs, &FieldError{Field: name, Rule: key, Message: name + " " + message})
+			}
+		}
+
+		// 递归校验嵌套的 struct
+		if field.Kind() == reflect.Struct {
+			if fieldInfo.Anonymous {
+				validateStruct(field, prefix, errs)
+			} else {
+				validateStruct(field, name+".", errs)
+			}
+		}
+	}
+}
+
+func splitRules(tag string) []string {
+	var items []string
+	for _, item := range strings.Split(tag, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func isEmpty(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return v.Len() == 0
+	default:
+		return v.IsZero()
+	}
+}
+
+// compare 比较 v 和 param，string 比较字符数，slice、map 比较长度，数字比较值
+func compare(v reflect.Value, param string) int {
+	var value float64
+	switch v.Kind() {
+	case reflect.String:
+		value = float64(utf8.RuneCountInString(v.String()))
+	case reflect.Slice, reflect.Map, reflect.Array:
+		value = float64(v.Len())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		value = float64(v.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		value = float64(v.Uint())
+	case reflect.Float32, reflect.Float64:
+		value = v.Float()
+	default:
+		panic(fmt.Sprintf("binding: min/max is not supported for %s", v.Type()))
+	}
+	limit, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		panic(fmt.Sprintf("binding: invalid min/max parameter %q", param))
+	}
+	switch {
+	case value < limit:
+		return -1
+	case value > limit:
+		return 1
+	}
+	return 0
+}
+
+func unit(v reflect.Value) string {
+	switch v.Kind() {
+	case reflect.String:
+		return " characters"
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return " items"
+	}
+	return ""
+}
@@ -0,0 +1,332 @@
+package gee
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// websocketGUID 是 RFC 6455 中用于计算 Sec-WebSocket-Accept 的固定值
+const websocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// 消息类型，对应 RFC 6455 中帧的 opcode
+const (
+	continuationFrame = 0
+	TextMessage       = 1
+	BinaryMessage     = 2
+	CloseMessage      = 8
+	PingMessage       = 9
+	PongMessage       = 10
+)
+
+// 关闭连接时的状态码
+const (
+	CloseNormalClosure    = 1000
+	CloseGoingAway        = 1001
+	CloseProtocolError    = 1002
+	CloseNoStatusReceived = 1005 // 对方的关闭帧中没有状态码，不能出现在发送的关闭帧中
+	CloseInvalidPayload   = 1007
+	CloseMessageTooBig    = 1009
+)
+
+// defaultReadLimit 是单条消息默认的最大字节数
+const defaultReadLimit = 1 << 20
+
+var (
+	ErrBadHandshake = errors.New("websocket: bad handshake")
+	ErrCloseSent    = errors.New("websocket: close sent")
+)
+
+// CloseError 表示连接已经关闭，Code 为对方发送的状态码或者因为对方违反协议而发送的状态码
+type CloseError struct {
+	Code int
+	Text string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: close %d %s", e.Code, e.Text)
+}
+
+// WebSocket 是升级后的连接，ReadMessage 只能在一个 goroutine 中调用，写入的方法可以并发调用
+type WebSocket struct {
+	ReadLimit int64 // 单条消息的最大字节数，超过时以 CloseMessageTooBig 关闭连接
+
+	conn net.Conn
+	br   *bufio.Reader
+
+	mu        sync.Mutex // 保护写入
+	closeSent bool
+}
+
+// WebSocket 完成 WebSocket 握手并接管连接，之后不能再使用 ctx.Writer；
+// 握手失败时通过 ctx.Error 返回 400、403 或 426，并返回 ErrBadHandshake
+func (ctx *Context) WebSocket() (*WebSocket, error) {
+	req := ctx.Request
+	if req.Method != http.MethodGet || !headerContains(req.Header, "Connection", "upgrade") ||
+		!headerContains(req.Header, "Upgrade", "websocket") {
+		ctx.Fail(http.StatusBadRequest, "websocket: not a websocket handshake")
+		return nil, ErrBadHandshake
+	}
+	if req.Header.Get("Sec-WebSocket-Version") != "13" {
+		ctx.SetHeader("Sec-WebSocket-Version", "13")
+		ctx.Fail(http.StatusUpgradeRequired, "websocket: unsupported version")
+		return nil, ErrBadHandshake
+	}
+	key := req.Header.Get("Sec-WebSocket-Key")
+	if decoded, err := base64.StdEncoding.DecodeString(key); err != nil || len(decoded) != 16 {
+		ctx.Fail(http.StatusBadRequest, "websocket: invalid Sec-WebSocket-Key")
+		return nil, ErrBadHandshake
+	}
+
+	checkOrigin := ctx.engine.CheckOrigin
+	if checkOrigin == nil {
+		checkOrigin = sameOrigin
+	}
+	if !checkOrigin(req) {
+		ctx.Fail(http.StatusForbidden, "websocket: origin not allowed")
+		return nil, ErrBadHandshake
+	}
+
+	// 状态码只用于 Logger 等 middleware，响应由下面直接写入连接
+	ctx.SetStatus(http.StatusSwitchingProtocols)
+	conn, brw, err := ctx.Writer.Hijack()
+	if err != nil {
+		ctx.Error(err)
+		return nil, err
+	}
+	// 接管后的连接可能带有 Server 设置的超时
+	conn.SetDeadline(time.Time{})
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &WebSocket{ReadLimit: defaultReadLimit, conn: conn, br: brw.Reader}, nil
+}
+
+// sameOrigin 是默认的 CheckOrigin，没有 Origin 的请求不是来自浏览器，直接接受
+func sameOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	return strings.EqualFold(u.Host, r.Host)
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + websocketGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// headerContains 判断 header 中以逗号分隔的值是否包含 token，不区分大小写
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header.Values(name) {
+		for _, item := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(item), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage 读取一条完整的消息，分片的消息会被拼接起来；
+// Ping 自动回复 Pong，收到关闭帧时回复相同的状态码并关闭连接，返回 *CloseError
+func (ws *WebSocket) ReadMessage() (messageType int, data []byte, err error) {
+	for {
+		fin, opcode, payload, err := ws.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch opcode {
+		case PingMessage:
+			if err := ws.writeFrame(PongMessage, payload); err != nil && err != ErrCloseSent {
+				return 0, nil, err
+			}
+			continue
+		case PongMessage:
+			continue
+		case CloseMessage:
+			closeErr := &CloseError{Code: CloseNoStatusReceived}
+			var reply []byte
+			if len(payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(payload))
+				closeErr.Text = string(payload[2:])
+				reply = payload[:2]
+			}
+			ws.writeFrame(CloseMessage, reply)
+			ws.conn.Close()
+			return 0, nil, closeErr
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "previous message is not finished")
+			}
+			messageType = opcode
+		case continuationFrame:
+			if messageType == 0 {
+				return 0, nil, ws.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+		default:
+			return 0, nil, ws.fail(CloseProtocolError, fmt.Sprintf("unknown opcode %d", opcode))
+		}
+
+		if int64(len(data)+len(payload)) > ws.ReadLimit {
+			return 0, nil, ws.fail(CloseMessageTooBig, "message is too big")
+		}
+		data = append(data, payload...)
+		if fin {
+			if messageType == TextMessage && !utf8.Valid(data) {
+				return 0, nil, ws.fail(CloseInvalidPayload, "invalid utf-8 text")
+			}
+			return messageType, data, nil
+		}
+	}
+}
+
+// readFrame 读取一个帧，客户端发送的帧必须带有掩码
+func (ws *WebSocket) readFrame() (fin bool, opcode int, payload []byte, err error) {
+	var header [8]byte
+	if _, err = io.ReadFull(ws.br, header[:2]); err != nil {
+		return
+	}
+	fin = header[0]&0x80 != 0
+	opcode = int(header[0] & 0x0f)
+	if header[0]&0x70 != 0 {
+		return fin, opcode, nil, ws.fail(CloseProtocolError, "reserved bits are set")
+	}
+	masked := header[1]&0x80 != 0
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		if _, err = io.ReadFull(ws.br, header[:2]); err != nil {
+			return
+		}
+		length = uint64(binary.BigEndian.Uint16(header[:2]))
+	case 127:
+		if _, err = io.ReadFull(ws.br, header[:8]); err != nil {
+			return
+		}
+		length = binary.BigEndian.Uint64(header[:8])
+	}
+
+	if !masked {
+		return fin, opcode, nil, ws.fail(CloseProtocolError, "client frame is not masked")
+	}
+	if opcode >= CloseMessage && (length > 125 || !fin) {
+		return fin, opcode, nil, ws.fail(CloseProtocolError, "invalid control frame")
+	}
+	if length > uint64(ws.ReadLimit) {
+		return fin, opcode, nil, ws.fail(CloseMessageTooBig, "message is too big")
+	}
+
+	var mask [4]byte
+	if _, err = io.ReadFull(ws.br, mask[:]); err != nil {
+		return
+	}
+	payload = make([]byte, length)
+	if _, err = io.ReadFull(ws.br, payload); err != nil {
+		return
+	}
+	for i := range payload {
+		payload[i] ^= mask[i%4]
+	}
+	return fin, opcode, payload, nil
+}
+
+// fail 因为对方违反协议发送关闭帧并关闭连接
+func (ws *WebSocket) fail(code int, text string) error {
+	ws.Close(code, text)
+	return &CloseError{Code: code, Text: text}
+}
+
+// WriteMessage 以一个帧发送消息，messageType 为 TextMessage、BinaryMessage、PingMessage 或 PongMessage
+func (ws *WebSocket) WriteMessage(messageType int, data []byte) error {
+	switch messageType {
+	case TextMessage, BinaryMessage:
+	case PingMessage, PongMessage:
+		if len(data) > 125 {
+			return errors.New("websocket: control frame payload is too big")
+		}
+	default:
+		return fmt.Errorf("websocket: invalid message type %d", messageType)
+	}
+	return ws.writeFrame(messageType, data)
+}
+
+// Ping 发送 Ping，对方回复的 Pong 在 ReadMessage 中被忽略，可以定期调用以保持连接
+func (ws *WebSocket) Ping(data []byte) error {
+	return ws.WriteMessage(PingMessage, data)
+}
+
+// Close 发送关闭帧并关闭连接，不等待对方回复关闭帧
+func (ws *WebSocket) Close(code int, text string) error {
+	payload := make([]byte, 2, 2+len(text))
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	payload = append(payload, text...)
+	if len(payload) > 125 {
+		payload = payload[:125]
+	}
+	err := ws.writeFrame(CloseMessage, payload)
+	if closeErr := ws.conn.Close(); err == nil {
+		err = closeErr
+	}
+	if err == ErrCloseSent {
+		return nil
+	}
+	return err
+}
+
+func (ws *WebSocket) SetReadDeadline(t time.Time) error {
+	return ws.conn.SetReadDeadline(t)
+}
+
+func (ws *WebSocket) SetWriteDeadline(t time.Time) error {
+	return ws.conn.SetWriteDeadline(t)
+}
+
+// writeFrame 发送一个完整的帧，服务端发送的帧不带掩码；发送关闭帧之后不能再发送
+func (ws *WebSocket) writeFrame(opcode int, data []byte) error {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	if ws.closeSent {
+		return ErrCloseSent
+	}
+	if opcode == CloseMessage {
+		ws.closeSent = true
+	}
+
+	frame := make([]byte, 0, len(data)+10)
+	frame = append(frame, 0x80|byte(opcode))
+	switch n := len(data); {
+	case n <= 125:
+		frame = append(frame, byte(n))
+	case n <= 0xffff:
+		frame = append(frame, 126, byte(n>>8), byte(n))
+	default:
+		var length [8]byte
+		binary.BigEndian.PutUint64(length[:], uint64(n))
+		frame = append(append(frame, 127), length[:]...)
+	}
+	frame = append(frame, data...)
+	_, err := ws.conn.Write(frame)
+	return err
+}
@@ -0,0 +1,253 @@
+package gee
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// wsClient 是测试用的 WebSocket 客户端，发送的帧带有掩码
+type wsClient struct {
+	conn net.Conn
+	br   *bufio.Reader
+}
+
+func dialWebSocket(t *testing.T, server *httptest.Server, path string) *wsClient {
+	conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := "dGhlIHNhbXBsZSBub25jZQ=="
+	request := "GET " + path + " HTTP/1.1\r\nHost: gee\r\nUpgrade: websocket\r\nConnection: keep-alive, Upgrade\r\n" +
+		"Sec-WebSocket-Key: " + key + "\r\nSec-WebSocket-Version: 13\r\n\r\n"
+	if _, err := conn.Write([]byte(request)); err != nil {
+		t.Fatal(err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// RFC 6455 中的示例
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Fatal("bad handshake response:", resp.Status, resp.Header)
+	}
+	return &wsClient{conn: conn, br: br}
+}
+
+func (c *wsClient) writeFrame(fin bool, opcode int, payload []byte) {
+	b0 := byte(opcode)
+	if fin {
+		b0 |= 0x80
+	}
+	frame := []byte{b0}
+	switch n := len(payload); {
+	case n <= 125:
+		frame = append(frame, 0x80|byte(n))
+	default:
+		frame = append(frame, 0x80|126, byte(n>>8), byte(n))
+	}
+	mask := []byte{1, 2, 3, 4}
+	frame = append(frame, mask...)
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+	c.conn.Write(frame)
+}
+
+func (c *wsClient) readFrame(t *testing.T) (int, []byte) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.br, header[:]); err != nil {
+		t.Fatal(err)
+	}
+	if header[1]&0x80 != 0 {
+		t.Fatal("server frame should not be masked")
+	}
+	length := int(header[1] & 0x7f)
+	if length == 126 {
+		var ext [2]byte
+		io.ReadFull(c.br, ext[:])
+		length = int(binary.BigEndian.Uint16(ext[:]))
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.br, payload); err != nil {
+		t.Fatal(err)
+	}
+	return int(header[0] & 0x0f), payload
+}
+
+func closePayload(code int, text string) []byte {
+	payload := make([]byte, 2)
+	binary.BigEndian.PutUint16(payload, uint16(code))
+	return append(payload, text...)
+}
+
+func newEchoServer(t *testing.T, closed chan<- error) *httptest.Server {
+	engine := New()
+	engine.GET("/ws", func(ctx *Context) {
+		ws, err := ctx.WebSocket()
+		if err != nil {
+			return
+		}
+		for {
+			messageType, data, err := ws.ReadMessage()
+			if err != nil {
+				closed <- err
+				return
+			}
+			if err := ws.WriteMessage(messageType, data); err != nil {
+				closed <- err
+				return
+			}
+		}
+	})
+	return httptest.NewServer(engine)
+}
+
+func TestWebSocket(t *testing.T) {
+	closed := make(chan error, 1)
+	server := newEchoServer(t, closed)
+	defer server.Close()
+	client := dialWebSocket(t, server, "/ws")
+	defer client.conn.Close()
+
+	client.writeFrame(true, TextMessage, []byte("hello"))
+	if opcode, payload := client.readFrame(t); opcode != TextMessage || string(payload) != "hello" {
+		t.Fatal("should echo hello, got:", opcode, string(payload))
+	}
+
+	// 分片的消息中间插入 Ping，服务端先回复 Pong，再返回拼接后的消息
+	large := bytes.Repeat([]byte("x"), 300)
+	client.writeFrame(false, BinaryMessage, large[:100])
+	client.writeFrame(true, PingMessage, []byte("ping"))
+	client.writeFrame(true, continuationFrame, large[100:])
+	if opcode, payload := client.readFrame(t); opcode != PongMessage || string(payload) != "ping" {
+		t.Fatal("should reply pong, got:", opcode, string(payload))
+	}
+	if opcode, payload := client.readFrame(t); opcode != BinaryMessage || !bytes.Equal(payload, large) {
+		t.Fatal("should echo fragmented message, got:", opcode, len(payload))
+	}
+
+	// 关闭握手：服务端回复相同的状态码
+	client.writeFrame(true, CloseMessage, closePayload(CloseNormalClosure, "bye"))
+	if opcode, payload := client.readFrame(t); opcode != CloseMessage || !bytes.Equal(payload, closePayload(CloseNormalClosure, "")) {
+		t.Fatal("should reply close, got:", opcode, payload)
+	}
+	var closeErr *CloseError
+	if err := <-closed; !errors.As(err, &closeErr) || closeErr.Code != CloseNormalClosure || closeErr.Text != "bye" {
+		t.Fatal("ReadMessage should return CloseError, got:", err)
+	}
+}
+
+func TestWebSocketProtocolError(t *testing.T) {
+	closed := make(chan error, 1)
+	server := newEchoServer(t, closed)
+	defer server.Close()
+
+	tests := []struct {
+		name string
+		send func(c *wsClient)
+		code int
+	}{
+		{"unmasked", func(c *wsClient) { c.conn.Write([]byte{0x81, 0x02, 'h', 'i'}) }, CloseProtocolError},
+		{"invalid utf-8", func(c *wsClient) { c.writeFrame(true, TextMessage, []byte{0xff, 0xfe}) }, CloseInvalidPayload},
+		{"continuation", func(c *wsClient) { c.writeFrame(true, continuationFrame, []byte("x")) }, CloseProtocolError},
+		{"fragmented ping", func(c *wsClient) { c.writeFrame(false, PingMessage, nil) }, CloseProtocolError},
+	}
+	for _, tt := range tests {
+		client := dialWebSocket(t, server, "/ws")
+		tt.send(client)
+		opcode, payload := client.readFrame(t)
+		if opcode != CloseMessage || int(binary.BigEndian.Uint16(payload)) != tt.code {
+			t.Fatalf("%s: should close with %d, got %d %v", tt.name, tt.code, opcode, payload)
+		}
+		var closeErr *CloseError
+		if err := <-closed; !errors.As(err, &closeErr) || closeErr.Code != tt.code {
+			t.Fatalf("%s: ReadMessage should return CloseError %d, got %v", tt.name, tt.code, err)
+		}
+		client.conn.Close()
+	}
+}
+
+func TestWebSocketBadHandshake(t *testing.T) {
+	server := newEchoServer(t, make(chan error, 1))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/ws")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Fatal("plain GET should be 400, got:", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest("GET", server.URL+"/ws", nil)
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "8")
+	resp, err = http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUpgradeRequired || resp.Header.Get("Sec-WebSocket-Version") != "13" {
+		t.Fatal("unsupported version should be 426, got:", resp.StatusCode)
+	}
+}
+
+func TestWebSocketOrigin(t *testing.T) {
+	engine := New()
+	engine.GET("/ws", func(ctx *Context) {
+		if ws, err := ctx.WebSocket(); err == nil {
+			ws.Close(CloseNormalClosure, "")
+		}
+	})
+	server := httptest.NewServer(engine)
+	defer server.Close()
+
+	handshake := func(origin string) int {
+		req, _ := http.NewRequest("GET", server.URL+"/ws", nil)
+		req.Header.Set("Connection", "Upgrade")
+		req.Header.Set("Upgrade", "websocket")
+		req.Header.Set("Sec-WebSocket-Version", "13")
+		req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+		if origin != "" {
+			req.Header.Set("Origin", origin)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	for origin, want := range map[string]int{
+		"":                      http.StatusSwitchingProtocols,
+		server.URL:              http.StatusSwitchingProtocols,
+		"http://evil.example":   http.StatusForbidden,
+		"http://%zz-not-an-url": http.StatusForbidden,
+	} {
+		if got := handshake(origin); got != want {
+			t.Fatalf("origin %q: want %d, got %d", origin, want, got)
+		}
+	}
+
+	engine.CheckOrigin = func(r *http.Request) bool {
+		return r.Header.Get("Origin") == "http://evil.example"
+	}
+	if got := handshake("http://evil.example"); got != http.StatusSwitchingProtocols {
+		t.Fatal("CheckOrigin should allow the origin, got:", got)
+	}
+	if got := handshake(server.URL); got != http.StatusForbidden {
+		t.Fatal("CheckOrigin should reject the origin, got:", got)
+	}
+}
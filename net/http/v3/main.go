@@ -1,16 +1,25 @@
 package main
 
 import (
+	"context"
 	"fmt"
 	"html/template"
+	"log"
 	"net/http"
+	"os/signal"
+	"syscall"
 	"time"
 
 	"github.com/go-examples-with-tests/net/http/v3/gee"
+	"golang.org/x/sync/errgroup"
 )
 
 func main() {
 	engine := gee.Default()
+	engine.ReadTimeout = 5 * time.Second
+	engine.WriteTimeout = 10 * time.Second
+	engine.IdleTimeout = time.Minute
+	engine.MaxHeaderBytes = 1 << 20
 
 	engine.GET("/json", func(ctx *gee.Context) {
 		obj := gee.H{
@@ -36,7 +45,7 @@ func main() {
 		ctx.String(http.StatusOK, "Hello, %s!", username)
 	})
 
-	engine.GET("/assets/*filepath", func(ctx *gee.Context) {
+	engine.GET("/files/*filepath", func(ctx *gee.Context) {
 		ctx.JSON(http.StatusOK, gee.H{"filepath": ctx.Param("filepath")})
 	})
 
@@ -106,7 +115,56 @@ func main() {
 		})
 	})
 
-	engine.Run(":9999")
+	// example: curl -N "http://localhost:9999/clock"，WriteTimeout 限制了推送的时长
+	engine.GET("/clock", func(ctx *gee.Context) {
+		ticker := time.NewTicker(time.Second)
+		defer ticker.Stop()
+		for i := 0; i < 5; i++ {
+			if err := ctx.SSEvent("tick", time.Now().Format(time.RFC3339)); err != nil {
+				return
+			}
+			<-ticker.C
+		}
+	})
+
+	// WebSocket 接管连接后不受 WriteTimeout 的限制
+	engine.GET("/echo", func(ctx *gee.Context) {
+		ws, err := ctx.WebSocket()
+		if err != nil {
+			return
+		}
+		for {
+			messageType, data, err := ws.ReadMessage()
+			if err != nil {
+				log.Println(err)
+				return
+			}
+			if err := ws.WriteMessage(messageType, data); err != nil {
+				return
+			}
+		}
+	})
+
+	// 收到 SIGINT 或 SIGTERM 后等待正在处理的请求结束再退出
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	// Run 出错时 ctx 同样结束，避免 Wait 一直阻塞
+	eg, ctx := errgroup.WithContext(ctx)
+	eg.Go(func() error {
+		return engine.Run(":9999")
+	})
+	// 和 net/http/v5 一样同时提供 HTTPS 服务：
+	// eg.Go(func() error { return engine.RunTLS(":9443", "server.pem", "server.key") })
+	eg.Go(func() error {
+		<-ctx.Done()
+		log.Println("shutting down server...")
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		return engine.Shutdown(shutdownCtx)
+	})
+	if err := eg.Wait(); err != nil {
+		log.Fatal(err)
+	}
 }
 
 func FormatAsDate(t time.Time) string {
@@ -10,11 +10,16 @@ type cache struct {
 	lock       sync.Mutex // 无需初始化，直接就可使用
 	lru        *lru.Cache
 	cacheBytes int64
+	generation uint64 // remove 的次数，加载开始之后发生过 remove 时，加载的结果可能已经过期
 }
 
-func (c *cache) add(key string, view ByteView) {
+// add 只在 generation 等于加载开始时的 gen 时写入，否则丢弃加载的结果，避免旧数据覆盖 remove 并一直留在缓存中
+func (c *cache) add(key string, view ByteView, gen uint64) {
 	c.lock.Lock()
 	defer c.lock.Unlock()
+	if gen != c.generation {
+		return
+	}
 
 	if c.lru == nil {
 		c.lru = lru.New(c.cacheBytes, nil) // lru.Cache函数返回的是 *lru.Cache 类型值
@@ -34,3 +39,20 @@ func (c *cache) get(key string) (v ByteView, ok bool) {
 	}
 	return
 }
+
+// gen 返回当前的 generation，在加载数据之前调用
+func (c *cache) gen() uint64 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.generation
+}
+
+func (c *cache) remove(key string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.generation++
+	if c.lru == nil {
+		return
+	}
+	c.lru.Remove(key)
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 
 	"github.com/go-examples-with-tests/net/http/v4/cachepb"
 	"github.com/go-examples-with-tests/net/http/v4/singleflight"
@@ -16,6 +17,18 @@ type Group struct {
 
 	picker       PeerPicker
 	singleflight *singleflight.Group
+
+	stats Stats
+}
+
+// Stats 是 Group 的统计信息，Misses 为没有命中本地缓存的次数，
+// 同一个 key 的并发请求经过 singleflight 合并后只会 Load 一次，所以 Loads 可能小于 Misses
+type Stats struct {
+	Gets       int64
+	Hits       int64
+	Misses     int64
+	Loads      int64
+	LoadErrors int64
 }
 
 var (
@@ -40,20 +53,29 @@ func NewGroup(name string, cacheBytes int64, getter Getter) *Group {
 		panic("getter is nil")
 	}
 
+	mu.Lock()
+	defer mu.Unlock()
 	if _, exist := groups[name]; exist {
 		panic("group " + name + " exists")
 	}
 
-	mu.Lock()
-	defer mu.Unlock()
-	g := &Group{
+	g := NewLocalGroup(name, cacheBytes, getter)
+	groups[name] = g
+	return g
+}
+
+// NewLocalGroup 创建的 Group 不注册到全局的 groups 中，GetGroup 和远端节点都无法访问，
+// 不再使用时可以被回收，适用于只在进程内使用、生命周期较短的缓存
+func NewLocalGroup(name string, cacheBytes int64, getter Getter) *Group {
+	if getter == nil {
+		panic("getter is nil")
+	}
+	return &Group{
 		name:         name,
 		getter:       getter,
 		mainCache:    cache{cacheBytes: cacheBytes},
 		singleflight: &singleflight.Group{},
 	}
-	groups[name] = g
-	return g
 }
 
 func GetGroup(name string) *Group {
@@ -68,14 +90,34 @@ func (g *Group) Get(key string) (ByteView, error) {
 	if key == "" {
 		return ByteView{}, fmt.Errorf("key is required")
 	}
+	atomic.AddInt64(&g.stats.Gets, 1)
 	if v, ok := g.mainCache.get(key); ok {
+		atomic.AddInt64(&g.stats.Hits, 1)
 		log.Println("[GeeCache] hit")
 		return v, nil
 	}
 
+	atomic.AddInt64(&g.stats.Misses, 1)
 	return g.load(key)
 }
 
+// Remove 删除本地缓存中的 key，数据源中的数据被修改或删除后调用，下一次 Get 会重新加载；
+// 正在进行的加载可能读到了修改之前的数据，它们的结果不会写入缓存
+func (g *Group) Remove(key string) {
+	g.mainCache.remove(key)
+}
+
+// Stats 返回 Group 的统计信息
+func (g *Group) Stats() Stats {
+	return Stats{
+		Gets:       atomic.LoadInt64(&g.stats.Gets),
+		Hits:       atomic.LoadInt64(&g.stats.Hits),
+		Misses:     atomic.LoadInt64(&g.stats.Misses),
+		Loads:      atomic.LoadInt64(&g.stats.Loads),
+		LoadErrors: atomic.LoadInt64(&g.stats.LoadErrors),
+	}
+}
+
 func (g *Group) RegistePeers(picker PeerPicker) {
 	if g.picker != nil {
 		panic("RegistePeers called more than once")
@@ -86,6 +128,7 @@ func (g *Group) RegistePeers(picker PeerPicker) {
 func (g *Group) load(key string) (value ByteView, err error) {
 	// 不论是从远端获取还是本地获取，都仅做一次请求
 	view, err := g.singleflight.Do(key, func() (interface{}, error) {
+		atomic.AddInt64(&g.stats.Loads, 1)
 		if g.picker != nil {
 			if peer, ok := g.picker.PickPeer(key); ok {
 				if value, err = g.getFromPeer(peer, key); err == nil {
@@ -101,6 +144,7 @@ func (g *Group) load(key string) (value ByteView, err error) {
 	if err == nil {
 		return view.(ByteView), nil
 	}
+	atomic.AddInt64(&g.stats.LoadErrors, 1)
 	return
 }
 
@@ -119,16 +163,18 @@ func (g *Group) getFromPeer(peer PeerGetter, key string) (ByteView, error) {
 }
 
 func (g *Group) getLocally(key string) (ByteView, error) {
+	// 在读取数据源之前记录 generation，读取期间发生的 Remove 会使本次的结果不写入缓存
+	gen := g.mainCache.gen()
 	// 调用 getter 从数据源获取数据
 	bytes, err := g.getter.Get(key)
 	if err != nil {
 		return ByteView{}, err
 	}
 	value := ByteView{b: cloneBytes(bytes)}
-	g.populateCache(key, value)
+	g.populateCache(key, value, gen)
 	return value, nil
 }
 
-func (g *Group) populateCache(key string, value ByteView) {
-	g.mainCache.add(key, value)
+func (g *Group) populateCache(key string, value ByteView, gen uint64) {
+	g.mainCache.add(key, value, gen)
 }
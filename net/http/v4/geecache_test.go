@@ -63,3 +63,64 @@ func TestGet(t *testing.T) {
 		t.Fatalf("the value of unknow should be empty, but %s got", view)
 	}
 }
+
+func TestRemoveAndStats(t *testing.T) {
+	loads := 0
+	gee := NewGroup("remove score", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		if v, ok := db[key]; ok {
+			loads++
+			return []byte(v), nil
+		}
+		return nil, fmt.Errorf("%s not exist", key)
+	}))
+
+	_, _ = gee.Get("Tom")
+	_, _ = gee.Get("Tom")
+	gee.Remove("Tom")
+	if _, err := gee.Get("Tom"); err != nil || loads != 2 {
+		t.Fatalf("Tom should be loaded again after remove, loads: %d", loads)
+	}
+	_, _ = gee.Get("unknown")
+
+	expect := Stats{Gets: 4, Hits: 1, Misses: 3, Loads: 3, LoadErrors: 1}
+	if stats := gee.Stats(); stats != expect {
+		t.Fatalf("expect stats %+v, got %+v", expect, stats)
+	}
+}
+
+func TestNewLocalGroup(t *testing.T) {
+	gee := NewLocalGroup("local scores", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		return []byte(db[key]), nil
+	}))
+	if view, err := gee.Get("Tom"); err != nil || view.String() != "630" {
+		t.Fatalf("failed to get value of Tom, got: %s, %v", view, err)
+	}
+	if GetGroup("local scores") != nil {
+		t.Fatal("local group should not be registered")
+	}
+	// 名字相同的 Group 不会冲突
+	NewGroup("local scores", 2<<10, gee.getter)
+}
+
+func TestRemoveDuringLoad(t *testing.T) {
+	var gee *Group
+	loads := 0
+	gee = NewLocalGroup("remove during load", 2<<10, GetterFunc(func(key string) ([]byte, error) {
+		loads++
+		if loads == 1 {
+			// 模拟读取数据源之后、写入缓存之前，并发的修改删除了缓存
+			gee.Remove(key)
+		}
+		return []byte(db[key]), nil
+	}))
+
+	_, _ = gee.Get("Tom")
+	_, _ = gee.Get("Tom")
+	if loads != 2 {
+		t.Fatalf("value loaded before Remove should not be cached, loads: %d", loads)
+	}
+	_, _ = gee.Get("Tom")
+	if loads != 2 {
+		t.Fatalf("value loaded after Remove should be cached, loads: %d", loads)
+	}
+}
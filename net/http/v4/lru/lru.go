@@ -56,6 +56,16 @@ func (c *Cache) Get(key string) (value Value, ok bool) {
 	return
 }
 
+// Remove 删除 key 对应的节点，不会触发 onRemoved 回调
+func (c *Cache) Remove(key string) {
+	if ele, ok := c.cache[key]; ok {
+		kv := ele.Value.(*entry)
+		delete(c.cache, key)
+		c.ll.Remove(ele)
+		c.nbytes -= int64(len(kv.key)) + int64(kv.value.Len())
+	}
+}
+
 func (c *Cache) removeOldest() {
 	ele := c.ll.Back()
 	if ele != nil {
@@ -58,3 +58,18 @@ func TestOnEvicted(t *testing.T) {
 		t.Fatalf("call onRemoved failed, expect keys equals to %s", expect)
 	}
 }
+
+func TestRemove(t *testing.T) {
+	lru := New(int64(0), nil)
+	lru.Add("key1", String("1234"))
+	lru.Add("key2", String("5678"))
+	lru.Remove("key1")
+	lru.Remove("unknown")
+
+	if _, ok := lru.Get("key1"); ok || lru.Len() != 1 {
+		t.Fatal("remove key1 failed")
+	}
+	if lru.nbytes != int64(len("key2")+len("5678")) {
+		t.Fatal("nbytes should be updated after remove, got:", lru.nbytes)
+	}
+}